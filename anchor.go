@@ -0,0 +1,171 @@
+package WaveFunctionCollapse
+
+// Anchor pins patterns to a side of the output ("left", "bottom", "right" or "top"). The cells along a side hold
+// only the patterns of the anchors of that side, an exclusive anchor also bans its patterns from every other cell.
+// Lines adds the patterns whose outer row, or column for the left and right side, occurs in one of the rows or
+// columns of the sample, negative lines count from the end of the sample.
+type Anchor struct {
+	Side      string `json:"side"`
+	Patterns  []int  `json:"patterns,omitempty"`
+	Lines     []int  `json:"lines,omitempty"`
+	Exclusive bool   `json:"exclusive,omitempty"`
+}
+
+// AddAnchor adds an anchor, the lines of the anchor are resolved to patterns
+func (model *OverlappingModel) AddAnchor(anchor Anchor) error {
+	side := sideIndex(anchor.Side)
+	if side < 0 {
+		return WFCError("side not recognized: " + anchor.Side)
+	}
+
+	patterns := append([]int(nil), anchor.Patterns...)
+	for _, t := range patterns {
+		if t < 0 || t >= model.T {
+			return WFCError("pattern out of range")
+		}
+	}
+
+	for _, line := range anchor.Lines {
+		matches, err := model.matchLine(side, line)
+		if err != nil {
+			return err
+		}
+		for _, t := range matches {
+			if !contains(patterns, t) {
+				patterns = append(patterns, t)
+			}
+		}
+	}
+
+	if len(patterns) == 0 {
+		return WFCError("anchor has no patterns")
+	}
+
+	anchor.Patterns = patterns
+	model.Anchors = append(model.Anchors, anchor)
+	return nil
+}
+
+func (model *OverlappingModel) ClearAnchors() {
+	model.Anchors = nil
+}
+
+func sideIndex(side string) int {
+	for d, name := range Sides {
+		if name == side {
+			return d
+		}
+	}
+	return -1
+}
+
+// matchLine returns the patterns whose edge facing the side occurs in a row or column of the sample
+func (model *OverlappingModel) matchLine(side, line int) ([]int, error) {
+	smx, smy := len(model.Sample), len(model.Sample[0])
+	horizontal := side == 1 || side == 3
+
+	length, lines := smx, smy
+	if !horizontal {
+		length, lines = smy, smx
+	}
+	if line < 0 {
+		line += lines
+	}
+	if line < 0 || line >= lines {
+		return nil, WFCError("anchor line out of range")
+	}
+
+	source := func(k int) uint8 {
+		if horizontal {
+			return model.Sample[k][line]
+		}
+		return model.Sample[line][k]
+	}
+
+	//the row or column of the pattern at the side
+	outer := 0
+	if side == 1 || side == 2 {
+		outer = model.N - 1
+	}
+	edge := func(t, k int) uint8 {
+		if horizontal {
+			return model.Patterns[t][k+outer*model.N]
+		}
+		return model.Patterns[t][outer+k*model.N]
+	}
+
+	offsets := length - model.N + 1
+	if model.PeriodicInput {
+		offsets = length
+	}
+
+	matches := make([]int, 0)
+	for t := 0; t < model.T; t++ {
+		for offset := 0; offset < offsets; offset++ {
+			match := true
+			for k := 0; k < model.N && match; k++ {
+				match = edge(t, k) == source((offset+k)%length)
+			}
+			if match {
+				matches = append(matches, t)
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+// anchorSides returns a bit for every side of the output cell i lies on, the cells on the bottom and right side
+// are the ones whose patterns cover the last rows and columns of the output
+func (model *OverlappingModel) anchorSides(i int) (sides int) {
+	x, y := i%model.Fmx, i/model.Fmx
+	right, bottom := model.Fmx-model.N, model.Fmy-model.N
+
+	for d, on := range [4]bool{x == 0, y == bottom, x == right, y == 0} {
+		if on {
+			sides |= 1 << uint(d)
+		}
+	}
+	return
+}
+
+// applyAnchors bans the patterns that are not anchored to the sides of a cell and the exclusive patterns that are
+// anchored to none of them
+func (model *OverlappingModel) applyAnchors() {
+	var allowed [4][]bool
+	anchored := make([]int, model.T)
+	exclusive := make([]bool, model.T)
+
+	for _, anchor := range model.Anchors {
+		d := sideIndex(anchor.Side)
+		if allowed[d] == nil {
+			allowed[d] = make([]bool, model.T)
+		}
+		for _, t := range anchor.Patterns {
+			allowed[d][t] = true
+			anchored[t] |= 1 << uint(d)
+			exclusive[t] = exclusive[t] || anchor.Exclusive
+		}
+	}
+
+	for i := range model.SumsOfOnes {
+		if model.Excluded(i) {
+			continue
+		}
+
+		sides := model.anchorSides(i)
+		for t := 0; t < model.T; t++ {
+			if !model.Possible(i, t) {
+				continue
+			}
+
+			ban := exclusive[t] && sides&anchored[t] == 0
+			for d := range allowed {
+				ban = ban || sides&(1<<uint(d)) != 0 && allowed[d] != nil && !allowed[d][t]
+			}
+			if ban {
+				model.Ban(i, t)
+			}
+		}
+	}
+}
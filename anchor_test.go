@@ -0,0 +1,52 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestAnchors(t *testing.T) {
+	//a white sample with a black bottom row
+	sample := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := color.White
+			if y == 7 {
+				c = color.Black
+			}
+			sample.Set(x, y, c)
+		}
+	}
+
+	for _, exclusive := range []bool{false, true} {
+		model := NewOverlappingModel(sample, 3, 16, 16, true, false, 1, 0, 1)
+		if err := model.AddAnchor(Anchor{Side: "bottom", Lines: []int{-1}, Exclusive: exclusive}); err != nil {
+			t.Fatal(err)
+		}
+		anchored := model.Anchors[0].Patterns
+
+		elsewhere := false
+		for seed := int64(1); seed <= 5; seed++ {
+			model.SetSeed(seed)
+			if !model.Run(0) {
+				t.Fatalf("exclusive %v, seed %d contradicted", exclusive, seed)
+			}
+
+			for i, p := range model.Observed {
+				if model.Excluded(i) {
+					continue
+				}
+				bottom := model.anchorSides(i)&(1<<1) != 0
+				if bottom && !contains(anchored, p) {
+					t.Errorf("exclusive %v, seed %d: bottom cell %d holds pattern %d", exclusive, seed, i, p)
+				}
+				elsewhere = elsewhere || !bottom && contains(anchored, p)
+			}
+		}
+
+		if elsewhere == exclusive {
+			t.Errorf("exclusive %v: anchored patterns away from the side %v", exclusive, elsewhere)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package WaveFunctionCollapse
+
+import "context"
+
+type decision struct {
+	Cell, Pattern int
+	TrailLength   int
+}
+
+// SetBacktracking enables backtracking on contradictions. The budget limits the number of backtracks per run and
+// the depth limits the number of decisions that can be undone, 0 means unlimited for both.
+func (model *Model) SetBacktracking(budget, depth int) {
+	model.Backtracking = true
+	model.BacktrackBudget = budget
+	model.BacktrackDepth = depth
+}
+
+func (model *Model) decide(i, t int) {
+	model.decisions = append(model.decisions, decision{Cell: i, Pattern: t, TrailLength: len(model.trail)})
+
+	if model.BacktrackDepth <= 0 || len(model.decisions) <= model.BacktrackDepth {
+		return
+	}
+
+	//forget the oldest decision together with the part of the trail that is only needed to undo it
+	cut := model.decisions[1].TrailLength
+	model.trail = model.trail[:copy(model.trail, model.trail[cut:])]
+	model.decisions = model.decisions[:copy(model.decisions, model.decisions[1:])]
+	for k := range model.decisions {
+		model.decisions[k].TrailLength -= cut
+	}
+}
+
+// Backtrack undoes the last decision and bans the pattern that was chosen, it returns false if there is no
+// decision left to undo or if the backtrack budget is exhausted.
+func (model *Model) Backtrack() bool {
+	ok, _ := model.backtrack(context.Background())
+	return ok
+}
+
+// backtrack is Backtrack with a context for the propagation of the ban, the error reports an interrupted propagation
+func (model *Model) backtrack(ctx context.Context) (bool, error) {
+	n := len(model.decisions)
+	if n == 0 || (model.BacktrackBudget > 0 && model.backtracks >= model.BacktrackBudget) {
+		return false, nil
+	}
+
+	model.backtracks++
+	last := model.decisions[n-1]
+	model.decisions = model.decisions[:n-1]
+
+	model.undo(last.TrailLength)
+	model.Ban(last.Cell, last.Pattern)
+
+	return true, model.propagate(ctx)
+}
+
+func (model *Model) undo(length int) {
+	touched := make([]bool, len(model.SumsOfOnes))
+	cells := make([]int, 0)
+
+	for k := len(model.trail) - 1; k >= length; k-- {
+		i, t := model.trail[k].A, model.trail[k].B
+
+		model.Wave.Set(i*model.words*64 + t)
+		model.SumsOfOnes[i]++
+		w, wlw := model.weight(i, t)
+		model.SumsOfWeights[i] += w
+		model.SumsOfWeightLogWeights[i] += wlw
+
+		if !touched[i] {
+			touched[i] = true
+			cells = append(cells, i)
+		}
+	}
+
+	model.trail = model.trail[:length]
+	model.Stack = model.Stack[:0]
+	model.contradiction = -1
+
+	//the compatibility counts of a cell depend on the wave of its neighbours
+	recompute := make([]bool, len(model.SumsOfOnes))
+	for _, i := range cells {
+		model.Entropies[i] = entropy(model.SumsOfWeights[i], model.SumsOfWeightLogWeights[i])
+		model.Heuristic.Update(model, i)
+
+		recompute[i] = true
+		for d := 0; d < model.directions; d++ {
+			if i2, ok := model.topology.Neighbor(i, d); ok {
+				recompute[i2] = true
+			}
+		}
+	}
+
+	for i, ok := range recompute {
+		if ok {
+			model.recompute(i)
+		}
+	}
+
+	model.resetGlobals()
+}
+
+func (model *Model) recompute(i2 int) {
+	boundary := model.Excluded(i2)
+
+	for d := 0; d < model.directions; d++ {
+		opposite := model.topology.Opposite(d)
+		i1, ok := model.topology.Neighbor(i2, opposite)
+		ok = ok && !boundary
+
+		for t2 := 0; t2 < model.T; t2++ {
+			k := (i2*model.T+t2)*model.directions + d
+			if !model.Possible(i2, t2) {
+				model.Compatible[k] = 0
+				continue
+			}
+
+			supporters := model.Propagator[opposite][t2]
+			if !ok {
+				model.Compatible[k] = int32(len(supporters))
+				continue
+			}
+
+			count := int32(0)
+			for _, t1 := range supporters {
+				if model.Possible(i1, t1) {
+					count++
+				}
+			}
+			model.Compatible[k] = count
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBacktrackingRecoversFromContradictions(t *testing.T) {
+	contradicted := 0
+	for seed := int64(1); seed <= 20; seed++ {
+		if NewTiledModel(testTiles(), 24, 24, true, false, seed).Run(0) {
+			continue
+		}
+		contradicted++
+
+		limited := NewTiledModel(testTiles(), 24, 24, true, false, seed)
+		limited.SetBacktracking(1, 0)
+		if result := limited.Solve(context.Background(), 0); result.Backtracks > 1 {
+			t.Errorf("seed %d: %d backtracks with a budget of 1", seed, result.Backtracks)
+		}
+
+		//a budget of 0 is unlimited
+		model := NewTiledModel(testTiles(), 24, 24, true, false, seed)
+		model.SetBacktracking(0, 0)
+		result := model.Solve(context.Background(), 0)
+		if result.Status != StatusSuccess {
+			t.Errorf("seed %d: backtracking ended with %v", seed, result.Status)
+			continue
+		}
+		if result.Backtracks == 0 {
+			t.Errorf("seed %d: solved without backtracking", seed)
+		}
+		if !validAdjacency(model.Model) {
+			t.Errorf("seed %d: neighbouring cells do not agree", seed)
+		}
+	}
+
+	if contradicted == 0 {
+		t.Fatal("no seed contradicted without backtracking")
+	}
+}
@@ -0,0 +1,48 @@
+package WaveFunctionCollapse
+
+import "math/bits"
+
+// Bitset is a packed array of booleans
+type Bitset []uint64
+
+func NewBitset(length int) Bitset {
+	return make(Bitset, (length+63)/64)
+}
+
+func (set Bitset) Get(k int) bool {
+	return set[k>>6]&(1<<uint(k&63)) != 0
+}
+
+func (set Bitset) Set(k int) {
+	set[k>>6] |= 1 << uint(k&63)
+}
+
+func (set Bitset) Unset(k int) {
+	set[k>>6] &^= 1 << uint(k&63)
+}
+
+// Fill sets the first length bits and clears the remaining bits of the set
+func (set Bitset) Fill(length int) {
+	for w := range set {
+		switch {
+		case length >= 64:
+			set[w] = ^uint64(0)
+			length -= 64
+		case length > 0:
+			set[w] = 1<<uint(length) - 1
+			length = 0
+		default:
+			set[w] = 0
+		}
+	}
+}
+
+// First returns the index of the first set bit, or -1 if no bit is set
+func (set Bitset) First() int {
+	for w, word := range set {
+		if word != 0 {
+			return w*64 + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
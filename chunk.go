@@ -0,0 +1,257 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"image"
+	"image/color"
+)
+
+// Chunked is implemented by the models that can generate the chunks of an unbounded world. ChunkModel returns a
+// model with the same rules on a non-periodic grid of width x height cells, CellColor renders the pixels of a cell
+// of CellSize x CellSize pixels holding pattern t.
+type Chunked interface {
+	ChunkModel(width, height int, seed int64) *Model
+	CellSize() int
+	CellColor(t, x, y int) color.Color
+}
+
+// ChunkGenerator generates an unbounded world chunk by chunk. Every chunk is solved in a window around it, the
+// outer ring of the window is pinned to the chunks that are already generated. When a chunk keeps contradicting
+// at its seams the window grows: the border cells inside the neighbouring chunks are regenerated together with
+// the chunk and written back. Generate reports the neighbours it changed so they can be rendered again, and the
+// result depends on the order in which the chunks are generated unless the window never grows past Border.
+type ChunkGenerator struct {
+	Source                          Chunked
+	Width, Height                   int
+	WorldSeed                       int64
+	Border, MaxBorder               int
+	Attempts, Limit                 int
+	Backtracking                    bool
+	BacktrackBudget, BacktrackDepth int
+
+	chunks map[image.Point][]int
+}
+
+func NewChunkGenerator(source Chunked, width, height int, worldSeed int64) *ChunkGenerator {
+	return &ChunkGenerator{
+		Source:    source,
+		Width:     width,
+		Height:    height,
+		WorldSeed: worldSeed,
+		MaxBorder: 2,
+		Attempts:  3,
+		chunks:    make(map[image.Point][]int),
+	}
+}
+
+// ChunkSeed derives the seed of an attempt to generate the chunk at x, y from the world seed
+func (generator *ChunkGenerator) ChunkSeed(x, y, attempt int) int64 {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, []int64{generator.WorldSeed, int64(x), int64(y), int64(attempt)})
+	return int64(h.Sum64())
+}
+
+// Chunk returns a copy of the patterns of the cells of a generated chunk in row order
+func (generator *ChunkGenerator) Chunk(x, y int) ([]int, bool) {
+	cells, ok := generator.chunks[image.Point{X: x, Y: y}]
+	if !ok {
+		return nil, false
+	}
+	return append([]int(nil), cells...), true
+}
+
+// SetChunk stores a chunk generated earlier, e.g. loaded from disk
+func (generator *ChunkGenerator) SetChunk(x, y int, cells []int) error {
+	if len(cells) != generator.Width*generator.Height {
+		return WFCError("chunk size does not match the generator")
+	}
+	generator.chunks[image.Point{X: x, Y: y}] = append([]int(nil), cells...)
+	return nil
+}
+
+// Forget drops a generated chunk, it is generated again from its neighbours when it is requested next
+func (generator *ChunkGenerator) Forget(x, y int) {
+	delete(generator.chunks, image.Point{X: x, Y: y})
+}
+
+// Generate generates the chunk at x, y consistent with the chunks around it, a chunk that exists is returned as is.
+// Modified lists the generated neighbours whose cells were regenerated along with the chunk.
+func (generator *ChunkGenerator) Generate(ctx context.Context, x, y int) ([]int, []image.Point, error) {
+	if cells, ok := generator.Chunk(x, y); ok {
+		return cells, nil, nil
+	}
+
+	//the window is at least Border wide, also when MaxBorder is smaller
+	attempt := 0
+	for border := generator.Border; border <= generator.MaxBorder || border == generator.Border; border++ {
+		for k := 0; k < generator.Attempts || k == 0; k++ {
+			modified, err := generator.solve(ctx, x, y, border, generator.ChunkSeed(x, y, attempt))
+			attempt++
+
+			if err == nil {
+				cells, _ := generator.Chunk(x, y)
+				return cells, modified, nil
+			} else if err != ErrContradiction {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return nil, nil, ErrContradiction
+}
+
+// solve solves the window of the chunk at x, y with a border of cells around it and the pinned ring outside that,
+// it returns the neighbouring chunks in which the border changed cells
+func (generator *ChunkGenerator) solve(ctx context.Context, x, y, border int, seed int64) ([]image.Point, error) {
+	width, height := generator.Width+2*border+2, generator.Height+2*border+2
+	left, top := x*generator.Width-border-1, y*generator.Height-border-1
+
+	model := generator.Source.ChunkModel(width, height, seed)
+	model.Backtracking = generator.Backtracking
+	model.BacktrackBudget, model.BacktrackDepth = generator.BacktrackBudget, generator.BacktrackDepth
+
+	for wy := 0; wy < height; wy++ {
+		for wx := 0; wx < width; wx++ {
+			if wx > 0 && wy > 0 && wx < width-1 && wy < height-1 {
+				continue
+			}
+
+			if t, ok := generator.cell(left+wx, top+wy); ok {
+				if err := model.SelectAt(wx, wy, t); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := model.RunContext(ctx, generator.Limit); err != nil {
+		return nil, err
+	}
+
+	current := image.Point{X: x, Y: y}
+	generator.chunks[current] = make([]int, generator.Width*generator.Height)
+
+	//write the chunk and the regenerated border inside its neighbours back
+	modified := make([]image.Point, 0)
+	for wy := 1; wy < height-1; wy++ {
+		for wx := 1; wx < width-1; wx++ {
+			chunk, changed := generator.setCell(left+wx, top+wy, model.Observed[wx+wy*width])
+			if changed && chunk != current && !containsPoint(modified, chunk) {
+				modified = append(modified, chunk)
+			}
+		}
+	}
+
+	return modified, nil
+}
+
+func containsPoint(points []image.Point, p image.Point) bool {
+	for _, q := range points {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (generator *ChunkGenerator) locate(x, y int) (chunk image.Point, i int) {
+	chunk = image.Point{X: floorDiv(x, generator.Width), Y: floorDiv(y, generator.Height)}
+	i = x - chunk.X*generator.Width + (y-chunk.Y*generator.Height)*generator.Width
+	return
+}
+
+// cell returns the pattern of a cell in world coordinates if its chunk is generated
+func (generator *ChunkGenerator) cell(x, y int) (int, bool) {
+	chunk, i := generator.locate(x, y)
+	if cells, ok := generator.chunks[chunk]; ok {
+		return cells[i], true
+	}
+	return 0, false
+}
+
+// setCell sets a cell in world coordinates if its chunk is generated and reports whether the cell changed
+func (generator *ChunkGenerator) setCell(x, y, t int) (image.Point, bool) {
+	chunk, i := generator.locate(x, y)
+	cells, ok := generator.chunks[chunk]
+	if !ok || cells[i] == t {
+		return chunk, false
+	}
+	cells[i] = t
+	return chunk, true
+}
+
+// ChunkImage renders a generated chunk
+func (generator *ChunkGenerator) ChunkImage(x, y int) (image.Image, error) {
+	cells, ok := generator.Chunk(x, y)
+	if !ok {
+		return nil, WFCError("chunk is not generated")
+	}
+
+	size := generator.Source.CellSize()
+	img := image.NewRGBA(image.Rect(0, 0, generator.Width*size, generator.Height*size))
+	for i, t := range cells {
+		cx, cy := i%generator.Width*size, i/generator.Width*size
+		for py := 0; py < size; py++ {
+			for px := 0; px < size; px++ {
+				img.Set(cx+px, cy+py, generator.Source.CellColor(t, px, py))
+			}
+		}
+	}
+	return img, nil
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// chunkModel copies the rules of the model to a new non-periodic grid. Unsupported patterns are banned, a pattern
+// at the edge of a chunk must fit next to the chunks generated later.
+func (model *Model) chunkModel(width, height int, seed int64) *Model {
+	chunk := &Model{
+		Rules: &Rules{
+			T:          model.T,
+			Propagator: model.Propagator,
+			Weights:    model.Weights,
+			Topology:   SquareGrid{Width: width, Height: height},
+		},
+		Fmx:        width,
+		Fmy:        height,
+		RandomSeed: seed,
+	}
+	chunk.ImplClear = func() {
+		chunk.ClearModel()
+		chunk.BanUnsupported()
+	}
+	return chunk
+}
+
+// ChunkModel returns a model with the patterns of the overlapping model, without its ground and anchors
+func (model *OverlappingModel) ChunkModel(width, height int, seed int64) *Model {
+	return model.chunkModel(width, height, seed)
+}
+
+func (model *OverlappingModel) CellSize() int {
+	return 1
+}
+
+func (model *OverlappingModel) CellColor(t, x, y int) color.Color {
+	return model.ColorModel().Convert(model.Colors[model.Patterns[t][0]])
+}
+
+func (model *TiledModel) ChunkModel(width, height int, seed int64) *Model {
+	return model.chunkModel(width, height, seed)
+}
+
+func (model *TiledModel) CellSize() int {
+	return model.TileSize
+}
+
+func (model *TiledModel) CellColor(t, x, y int) color.Color {
+	return model.ColorModel().Convert(model.Tiles[t][x+y*model.TileSize])
+}
@@ -0,0 +1,100 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func testChunkGenerator(seed int64) *ChunkGenerator {
+	generator := NewChunkGenerator(NewTiledModel(testTiles(), 1, 1, false, false, 0), 8, 8, seed)
+	generator.Backtracking = true
+	return generator
+}
+
+func TestChunkSeams(t *testing.T) {
+	reported := 0
+	for border := 0; border <= 1; border++ {
+		generator := testChunkGenerator(1)
+		generator.Border = border
+		source := generator.Source.(*TiledModel)
+
+		generated := make([]image.Point, 0)
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 3; x++ {
+				before := make(map[image.Point][]int)
+				for _, chunk := range generated {
+					before[chunk], _ = generator.Chunk(chunk.X, chunk.Y)
+				}
+
+				_, modified, err := generator.Generate(context.Background(), x, y)
+				if err != nil {
+					t.Fatal(err)
+				}
+				reported += len(modified)
+
+				for _, chunk := range generated {
+					after, _ := generator.Chunk(chunk.X, chunk.Y)
+					if changed := !reflect.DeepEqual(before[chunk], after); changed != containsPoint(modified, chunk) {
+						t.Fatalf("border %d: chunk %v changed %v, reported %v", border, chunk, changed, modified)
+					}
+				}
+				generated = append(generated, image.Pt(x, y))
+			}
+		}
+
+		world := make([]int, 24*24)
+		for _, chunk := range generated {
+			cells, _ := generator.Chunk(chunk.X, chunk.Y)
+			for i, p := range cells {
+				world[chunk.X*8+i%8+(chunk.Y*8+i/8)*24] = p
+			}
+		}
+
+		grid := SquareGrid{Width: 24, Height: 24}
+		for i, p := range world {
+			for d := 0; d < 4; d++ {
+				if j, ok := grid.Neighbor(i, d); ok && !contains(source.Propagator[d][p], world[j]) {
+					t.Fatalf("border %d: cells %d and %d do not fit across a seam", border, i, j)
+				}
+			}
+		}
+	}
+
+	if reported == 0 {
+		t.Fatal("no neighbour was regenerated")
+	}
+}
+
+func TestChunkDeterminism(t *testing.T) {
+	first, second := testChunkGenerator(7), testChunkGenerator(7)
+	for _, chunk := range []image.Point{{0, 0}, {1, 0}, {0, 1}, {-1, 0}} {
+		a, _, err := first.Generate(context.Background(), chunk.X, chunk.Y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _, err := second.Generate(context.Background(), chunk.X, chunk.Y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("chunk %v differs between generators with the same seed", chunk)
+		}
+	}
+
+	//the returned cells are copies
+	cells, _ := first.Chunk(0, 0)
+	cells[0] = -1
+	if again, _ := first.Chunk(0, 0); again[0] == -1 {
+		t.Fatal("changing the returned cells changed the chunk")
+	}
+}
+
+func TestChunkBorderAboveMaxBorder(t *testing.T) {
+	generator := testChunkGenerator(1)
+	generator.Border, generator.MaxBorder = 1, 0
+	if _, _, err := generator.Generate(context.Background(), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+}
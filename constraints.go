@@ -0,0 +1,177 @@
+package WaveFunctionCollapse
+
+import "math/rand"
+
+// CellConstraint selects or bans a pattern in a cell, constraints are applied and propagated after the model is
+// cleared and before the first observation.
+type CellConstraint struct {
+	Cell    int  `json:"cell"`
+	Pattern int  `json:"pattern"`
+	Banned  bool `json:"banned,omitempty"`
+}
+
+// SelectAt restricts the cell at x, y to pattern t
+func (model *Model) SelectAt(x, y, t int) error {
+	return model.constrain(x, y, t, false)
+}
+
+// BanAt forbids pattern t in the cell at x, y
+func (model *Model) BanAt(x, y, t int) error {
+	return model.constrain(x, y, t, true)
+}
+
+// SelectCell restricts cell i, numbered as in the topology of the model, to pattern t
+func (model *Model) SelectCell(i, t int) error {
+	return model.constrainCell(i, t, false)
+}
+
+// BanCell forbids pattern t in cell i, numbered as in the topology of the model
+func (model *Model) BanCell(i, t int) error {
+	return model.constrainCell(i, t, true)
+}
+
+func (model *Model) ClearConstraints() {
+	model.Constraints = nil
+}
+
+func (model *Model) constrain(x, y, t int, banned bool) error {
+	if x < 0 || y < 0 || x >= model.Fmx || y >= model.Fmy {
+		return WFCError("cell out of range")
+	}
+	return model.constrainCell(x+y*model.Fmx, t, banned)
+}
+
+func (model *Model) constrainCell(i, t int, banned bool) error {
+	if i < 0 || i >= model.grid().Size() {
+		return WFCError("cell out of range")
+	}
+	if t < 0 || t >= model.T {
+		return WFCError("pattern out of range")
+	}
+
+	model.Constraints = append(model.Constraints, CellConstraint{Cell: i, Pattern: t, Banned: banned})
+	return nil
+}
+
+// CheckConstraints applies the constraints to a cleared wave without observing any cell, it returns
+// ErrContradiction when the constraints can not be satisfied together. The state of the model, its random number
+// generator included, is left as it was and the listeners are not notified.
+func (model *Model) CheckConstraints() error {
+	saved := model.save()
+	defer model.restore(saved)
+
+	model.Listeners = nil
+	if err := model.prepare(); err != nil {
+		return err
+	}
+
+	if !model.applyConstraints() {
+		return ErrContradiction
+	}
+	return nil
+}
+
+// modelState is a copy of the solve state of a model
+type modelState struct {
+	wave                                             Bitset
+	compatible                                       []int32
+	sumsOfOnes                                       []int
+	sumsOfWeights, sumsOfWeightLogWeights, entropies []float64
+	noise                                            []float64
+	stack, trail                                     []IntTuple
+	observed                                         []int
+	decisions                                        []decision
+	seed                                             int64
+	source                                           rand.Source
+	random                                           *rand.Rand
+	draws                                            uint64
+	listeners                                        []Listener
+
+	contradiction, backtracks, observations, propagations, bans int
+}
+
+func (model *Model) save() modelState {
+	return modelState{
+		wave:                   append(Bitset(nil), model.Wave...),
+		compatible:             append([]int32(nil), model.Compatible...),
+		sumsOfOnes:             append([]int(nil), model.SumsOfOnes...),
+		sumsOfWeights:          append([]float64(nil), model.SumsOfWeights...),
+		sumsOfWeightLogWeights: append([]float64(nil), model.SumsOfWeightLogWeights...),
+		entropies:              append([]float64(nil), model.Entropies...),
+		noise:                  append([]float64(nil), model.Noise...),
+		stack:                  append([]IntTuple(nil), model.Stack...),
+		trail:                  append([]IntTuple(nil), model.trail...),
+		observed:               append([]int(nil), model.Observed...),
+		decisions:              append([]decision(nil), model.decisions...),
+		seed:                   model.RandomSeed,
+		source:                 model.Source,
+		random:                 model.random,
+		draws:                  model.counter.draws,
+		listeners:              model.Listeners,
+		contradiction:          model.contradiction,
+		backtracks:             model.backtracks,
+		observations:           model.observations,
+		propagations:           model.propagations,
+		bans:                   model.bans,
+	}
+}
+
+func (model *Model) restore(state modelState) {
+	model.Wave = state.wave
+	model.Compatible = state.compatible
+	model.SumsOfOnes = state.sumsOfOnes
+	model.SumsOfWeights = state.sumsOfWeights
+	model.SumsOfWeightLogWeights = state.sumsOfWeightLogWeights
+	model.Entropies = state.entropies
+	model.Noise = state.noise
+	model.Stack = state.stack
+	model.trail = state.trail
+	model.Observed = state.observed
+	model.decisions = state.decisions
+	model.Listeners = state.listeners
+	model.contradiction = state.contradiction
+	model.backtracks = state.backtracks
+	model.observations, model.propagations, model.bans = state.observations, state.propagations, state.bans
+
+	//the source was reseeded by the check, replay it up to where it was
+	model.RandomSeed, model.Source = state.seed, state.source
+	if state.random == nil {
+		model.random = nil
+		model.counter = countingSource{}
+	} else {
+		model.reseed()
+		for k := uint64(0); k < state.draws; k++ {
+			model.counter.Int63()
+		}
+	}
+
+	if model.Wave != nil {
+		model.resetHeuristic()
+		model.resetGlobals()
+	}
+}
+
+func (model *Model) applyConstraints() bool {
+	if len(model.Constraints) == 0 && len(model.Globals) == 0 {
+		return model.contradiction == -1
+	}
+
+	for _, c := range model.Constraints {
+		if c.Banned {
+			if model.Possible(c.Cell, c.Pattern) {
+				model.Ban(c.Cell, c.Pattern)
+			}
+			continue
+		}
+
+		for t := 0; t < model.T; t++ {
+			if t != c.Pattern && model.Possible(c.Cell, t) {
+				model.Ban(c.Cell, t)
+			}
+		}
+	}
+
+	model.Propagate()
+
+	return model.contradiction == -1
+}
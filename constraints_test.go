@@ -0,0 +1,49 @@
+package WaveFunctionCollapse
+
+import "testing"
+
+func TestCheckConstraintsKeepsState(t *testing.T) {
+	info := testTiles()
+	model := NewTiledModel(info, 12, 12, false, false, 3)
+	reference := NewTiledModel(info, 12, 12, false, false, 3)
+	if !model.Run(0) || !reference.Run(0) {
+		t.Fatal("run failed")
+	}
+
+	observed := append([]int(nil), model.Observed...)
+
+	if err := model.SelectAt(2, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.CheckConstraints(); err != nil {
+		t.Fatalf("satisfiable constraints reported %v", err)
+	}
+	if err := model.BanAt(2, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.CheckConstraints(); err != ErrContradiction {
+		t.Fatalf("contradicting constraints reported %v", err)
+	}
+
+	for i := range observed {
+		if model.Observed[i] != observed[i] {
+			t.Fatalf("cell %d changed from %d to %d", i, observed[i], model.Observed[i])
+		}
+	}
+	if model.Random().Int63() != reference.Random().Int63() {
+		t.Fatal("the random number generator moved")
+	}
+}
+
+func TestCheckConstraintsBeforeRun(t *testing.T) {
+	model := NewTiledModel(testTiles(), 8, 8, false, false, 1)
+	if err := model.CheckConstraints(); err != nil {
+		t.Fatal(err)
+	}
+	if model.Wave != nil {
+		t.Fatal("the check initialized the model")
+	}
+	if !model.Run(0) {
+		t.Fatal("run after the check failed")
+	}
+}
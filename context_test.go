@@ -0,0 +1,97 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// interrupter calls f at the start of the propagation after the given number of observations
+type interrupter struct {
+	NopListener
+	observations int
+	f            func()
+}
+
+func (l *interrupter) OnObserve(i, t int, entropy float64) {
+	l.observations--
+}
+
+func (l *interrupter) OnPropagateStart() {
+	if l.observations == 0 {
+		l.f()
+	}
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	model := NewTiledModel(testTiles(), 24, 24, true, false, 1)
+	model.SetBacktracking(0, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	model.AddListener(&interrupter{observations: 50, f: cancel})
+
+	if err := model.RunContext(ctx, 0); err != ErrCancelled {
+		t.Fatalf("run returned %v", err)
+	}
+	if model.Wave == nil || model.Observed != nil || len(model.Stack) == 0 {
+		t.Fatal("the wave was not left as it was during the propagation")
+	}
+
+	//the interrupted run can be continued
+	if err := model.Resume(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if !validAdjacency(model.Model) {
+		t.Fatal("neighbouring cells do not agree")
+	}
+}
+
+func TestRunContextTimeout(t *testing.T) {
+	model := NewTiledModel(testTiles(), 24, 24, true, false, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	model.AddListener(&interrupter{observations: 50, f: func() { <-ctx.Done() }})
+
+	if err := model.RunContext(ctx, 0); err != ErrTimeout {
+		t.Fatalf("run returned %v", err)
+	}
+	if model.Wave == nil || model.Observed != nil {
+		t.Fatal("the wave was not left as it was")
+	}
+}
+
+type canceller struct {
+	NopListener
+	cancel func()
+}
+
+func (l canceller) OnContradiction(i int) {
+	l.cancel()
+}
+
+func TestBacktrackPropagationCancelled(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		if NewTiledModel(testTiles(), 24, 24, true, false, seed).Run(0) {
+			continue
+		}
+
+		model := NewTiledModel(testTiles(), 24, 24, true, false, seed)
+		model.SetBacktracking(0, 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		model.AddListener(canceller{cancel: cancel})
+
+		err := model.RunContext(ctx, 0)
+		cancel()
+		if err != ErrCancelled {
+			t.Fatalf("seed %d: run returned %v", seed, err)
+		}
+		if model.backtracks != 1 || len(model.Stack) == 0 {
+			t.Fatalf("seed %d: the propagation after the backtrack was not interrupted", seed)
+		}
+		return
+	}
+	t.Fatal("no seed contradicted without backtracking")
+}
@@ -0,0 +1,205 @@
+package WaveFunctionCollapse
+
+import "image/color"
+
+// Unsatisfiable is reported as the contradiction cell of a global constraint that can not be met by any cell
+const Unsatisfiable = -2
+
+// GlobalConstraint constrains the patterns of all cells together. Reset is called whenever the wave is rebuilt,
+// Ban after every ban, and Check once the propagation of the bans is done. Check may ban patterns itself and
+// returns a cell to report when the constraint can no longer be met, Unsatisfiable when there is no such cell, or
+// -1.
+type GlobalConstraint interface {
+	Reset(model *Model)
+	Ban(model *Model, i, t int)
+	Check(model *Model) int
+	Clone() GlobalConstraint
+}
+
+// AddGlobal adds a global constraint that is enforced during propagation
+func (model *Model) AddGlobal(constraint GlobalConstraint) {
+	model.Globals = append(model.Globals, constraint)
+}
+
+func (model *Model) ClearGlobals() {
+	model.Globals = nil
+}
+
+func (model *Model) resetGlobals() {
+	for _, g := range model.Globals {
+		g.Reset(model)
+	}
+}
+
+// enforceGlobals checks the global constraints and reports whether they banned patterns that need propagating
+func (model *Model) enforceGlobals() bool {
+	for _, g := range model.Globals {
+		if i := g.Check(model); i != -1 {
+			if model.contradiction == -1 {
+				model.contradiction = i
+			}
+			return false
+		}
+	}
+	return len(model.Stack) > 0
+}
+
+// CountConstraint keeps the number of cells holding one of its patterns between Min and Max, a negative Max means
+// there is no maximum. Once the maximum is reached the patterns are banned from every other cell, and when every
+// remaining candidate is needed to reach the minimum the candidates are forced to one of the patterns.
+type CountConstraint struct {
+	Patterns []int
+	Min, Max int
+
+	member            []bool
+	candidates        []int32
+	certain           []bool
+	possible, decided int
+}
+
+func NewCountConstraint(patterns []int, min, max int) *CountConstraint {
+	return &CountConstraint{Patterns: patterns, Min: min, Max: max}
+}
+
+func (c *CountConstraint) Clone() GlobalConstraint {
+	return NewCountConstraint(c.Patterns, c.Min, c.Max)
+}
+
+func (c *CountConstraint) Reset(model *Model) {
+	c.member = make([]bool, model.T)
+	for _, t := range c.Patterns {
+		c.member[t] = true
+	}
+
+	cells := len(model.SumsOfOnes)
+	c.candidates = make([]int32, cells)
+	c.certain = make([]bool, cells)
+	c.possible, c.decided = 0, 0
+
+	for i := 0; i < cells; i++ {
+		if model.Excluded(i) {
+			continue
+		}
+
+		for _, t := range c.Patterns {
+			if model.Possible(i, t) {
+				c.candidates[i]++
+			}
+		}
+		if c.candidates[i] > 0 {
+			c.possible++
+		}
+		c.update(model, i)
+	}
+}
+
+// update tracks whether every pattern left in cell i is one of the patterns
+func (c *CountConstraint) update(model *Model, i int) {
+	certain := c.candidates[i] > 0 && int(c.candidates[i]) == model.SumsOfOnes[i]
+	if certain != c.certain[i] {
+		c.certain[i] = certain
+		if certain {
+			c.decided++
+		} else {
+			c.decided--
+		}
+	}
+}
+
+func (c *CountConstraint) Ban(model *Model, i, t int) {
+	if model.Excluded(i) {
+		return
+	}
+
+	if c.member[t] {
+		c.candidates[i]--
+		if c.candidates[i] == 0 {
+			c.possible--
+		}
+	}
+	c.update(model, i)
+}
+
+func (c *CountConstraint) Check(model *Model) int {
+	if c.Max >= 0 && c.decided > c.Max {
+		return c.contradiction(func(i int) bool { return c.certain[i] })
+	}
+	if c.possible < c.Min {
+		//without a cell that lost the patterns the output has fewer cells than the minimum
+		return c.contradiction(func(i int) bool { return c.candidates[i] == 0 && !model.Excluded(i) })
+	}
+
+	//the undecided candidates have to give up the patterns, or have to take one of them
+	full := c.Max >= 0 && c.decided == c.Max && c.possible > c.decided
+	needed := c.possible == c.Min && c.decided < c.possible
+	if !full && !needed {
+		return -1
+	}
+
+	for i := range c.candidates {
+		if c.candidates[i] == 0 || c.certain[i] || model.Excluded(i) {
+			continue
+		}
+
+		for t := 0; t < model.T; t++ {
+			if c.member[t] == full && model.Possible(i, t) {
+				model.Ban(i, t)
+			}
+		}
+	}
+
+	return -1
+}
+
+// contradiction returns the cell to report the contradiction at, the first one for which f holds
+func (c *CountConstraint) contradiction(f func(i int) bool) int {
+	if i := c.find(f); i >= 0 {
+		return i
+	}
+	return Unsatisfiable
+}
+
+func (c *CountConstraint) find(f func(i int) bool) int {
+	for i := range c.candidates {
+		if f(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// CountTiles keeps the number of cells holding a tile name, in any orientation, between min and max
+func (model *TiledModel) CountTiles(name string, min, max int) error {
+	patterns, err := model.TilePatterns(name)
+	if err != nil {
+		return err
+	}
+
+	model.AddGlobal(NewCountConstraint(patterns, min, max))
+	return nil
+}
+
+// ColorPatterns returns the patterns that give their cell color c
+func (model *OverlappingModel) ColorPatterns(c color.Color) []int {
+	r, g, b, a := c.RGBA()
+	patterns := make([]int, 0)
+	for t, pattern := range model.Patterns {
+		pr, pg, pb, pa := model.Colors[pattern[0]].RGBA()
+		if pr == r && pg == g && pb == b && pa == a {
+			patterns = append(patterns, t)
+		}
+	}
+	return patterns
+}
+
+// CountColor keeps the number of cells with pixel color c between min and max, the cells on the boundary of a
+// non-periodic output are not counted
+func (model *OverlappingModel) CountColor(c color.Color, min, max int) error {
+	patterns := model.ColorPatterns(c)
+	if len(patterns) == 0 {
+		return WFCError("color does not occur in the sample")
+	}
+
+	model.AddGlobal(NewCountConstraint(patterns, min, max))
+	return nil
+}
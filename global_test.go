@@ -0,0 +1,43 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCountConstraintMinimumAboveCells(t *testing.T) {
+	model := NewTiledModel(testTiles(), 4, 4, false, false, 1)
+	if err := model.CountTiles("empty", 17, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	result := model.Solve(context.Background(), 0)
+	if result.Status != StatusContradiction {
+		t.Fatalf("status %v", result.Status)
+	}
+	if result.Contradiction == nil || result.Contradiction.Cell != Unsatisfiable {
+		t.Fatalf("contradiction %v is not unsatisfiable", result.Contradiction)
+	}
+}
+
+func TestCountConstraintRange(t *testing.T) {
+	model := NewTiledModel(testTiles(), 8, 8, false, false, 1)
+	model.SetBacktracking(1000, 0)
+	if err := model.CountTiles("cross", 2, 4); err != nil {
+		t.Fatal(err)
+	}
+	if !model.Run(0) {
+		t.Fatal("run failed")
+	}
+
+	patterns, _ := model.TilePatterns("cross")
+	count := 0
+	for _, p := range model.Observed {
+		if contains(patterns, p) {
+			count++
+		}
+	}
+	if count < 2 || count > 4 {
+		t.Errorf("%d crosses", count)
+	}
+}
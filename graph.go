@@ -0,0 +1,253 @@
+package WaveFunctionCollapse
+
+// GraphEdge is a directed edge between two nodes of a graph, the label selects the adjacency rules of the edge
+type GraphEdge struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Label string `json:"label"`
+}
+
+type graphSlot struct {
+	label string
+	slot  int
+}
+
+// DefaultMaxSlots is the number of edges of one label that may start, or end, at the same node by default
+const DefaultMaxSlots = 64
+
+// GraphTopology connects the nodes of a graph along labeled directed edges. Every edge gets a pair of directions,
+// one for the outgoing and one for the incoming end, shared with the other edges of the same label as long as no
+// node uses a direction twice. A label therefore needs as many direction pairs as the most edges of that label
+// at one node, and since a model keeps a count for every node, pattern and direction, a graph with high degree
+// nodes is expensive: a star of 1000 edges would need 2000 directions at every node. Nodes are limited to maxSlots
+// edges per label and end, or DefaultMaxSlots if maxSlots is not positive.
+type GraphTopology struct {
+	Nodes int
+
+	slots     []graphSlot
+	neighbors []int32
+}
+
+func NewGraphTopology(nodes int, edges []GraphEdge, maxSlots int) (*GraphTopology, error) {
+	graph := &GraphTopology{Nodes: nodes}
+	if maxSlots <= 0 {
+		maxSlots = DefaultMaxSlots
+	}
+
+	index := make(map[graphSlot]int)
+	used := make(map[IntTuple]int)
+	seen := make(map[GraphEdge]bool)
+
+	for _, edge := range edges {
+		if edge.From < 0 || edge.To < 0 || edge.From >= nodes || edge.To >= nodes {
+			return nil, WFCError("edge node out of range")
+		}
+		if seen[edge] {
+			return nil, WFCError("duplicate edge")
+		}
+		seen[edge] = true
+
+		//find the first slot of the label that is free at both ends
+		var k int
+		for slot := 0; ; slot++ {
+			if slot >= maxSlots {
+				return nil, WFCError("too many edges of one label at a node: " + edge.Label)
+			}
+
+			key := graphSlot{label: edge.Label, slot: slot}
+			p, ok := index[key]
+			if !ok {
+				p = len(graph.slots)
+				index[key] = p
+				graph.slots = append(graph.slots, key)
+			}
+
+			_, out := used[IntTuple{A: edge.From, B: 2 * p}]
+			_, in := used[IntTuple{A: edge.To, B: 2*p + 1}]
+			if !out && !in {
+				k = p
+				break
+			}
+		}
+
+		used[IntTuple{A: edge.From, B: 2 * k}] = edge.To
+		used[IntTuple{A: edge.To, B: 2*k + 1}] = edge.From
+	}
+
+	directions := graph.Directions()
+	graph.neighbors = make([]int32, nodes*directions)
+	for i := range graph.neighbors {
+		graph.neighbors[i] = -1
+	}
+	for e, node := range used {
+		graph.neighbors[e.A*directions+e.B] = int32(node)
+	}
+
+	return graph, nil
+}
+
+func (graph *GraphTopology) Size() int {
+	return graph.Nodes
+}
+
+func (graph *GraphTopology) Directions() int {
+	return 2 * len(graph.slots)
+}
+
+func (graph *GraphTopology) Opposite(d int) int {
+	return d ^ 1
+}
+
+func (graph *GraphTopology) Neighbor(i, d int) (int, bool) {
+	j := graph.neighbors[i*graph.Directions()+d]
+	return int(j), j >= 0
+}
+
+func (graph *GraphTopology) Boundary(i int) bool {
+	return false
+}
+
+// Label returns the edge label of direction d and whether it points along the edge to its target
+func (graph *GraphTopology) Label(d int) (label string, outgoing bool) {
+	return graph.slots[d/2].label, d%2 == 0
+}
+
+type GraphTile struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+// GraphRule allows a tile at the source of an edge with a label next to a tile at its target: ["from", "label", "to"]
+type GraphRule [3]string
+
+// GraphModelInfo holds the tiles and rules of a graph model, MaxSlots limits the edges of one label at a node as in
+// NewGraphTopology
+type GraphModelInfo struct {
+	Tiles    []GraphTile `json:"tiles"`
+	Rules    []GraphRule `json:"rules"`
+	MaxSlots int         `json:"max_slots,omitempty"`
+}
+
+// GraphModel places a tile on every node of a graph, the tiles at the ends of each edge must be allowed by a rule
+// of the edge label
+type GraphModel struct {
+	*Model
+
+	Graph     *GraphTopology
+	TileNames []string
+}
+
+func NewGraphModel(info GraphModelInfo, nodes int, edges []GraphEdge, seed int64) (model *GraphModel, err error) {
+	graph, err := NewGraphTopology(nodes, edges, info.MaxSlots)
+	if err != nil {
+		return nil, err
+	}
+
+	model = &GraphModel{
+		Model: &Model{
+			Rules:      &Rules{T: len(info.Tiles), Topology: graph},
+			Fmx:        nodes,
+			Fmy:        1,
+			RandomSeed: seed,
+		},
+		Graph: graph,
+	}
+	model.ImplClear = model.Clear
+
+	model.TileNames = make([]string, model.T)
+	model.Weights = make([]float64, model.T)
+	for t, tile := range info.Tiles {
+		model.TileNames[t] = tile.Name
+		model.Weights[t] = tile.Weight
+	}
+
+	allowed := make(map[string][][]bool)
+	for _, rule := range info.Rules {
+		from, err := model.tileIndex(rule[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := model.tileIndex(rule[2])
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := allowed[rule[1]]; !ok {
+			allowed[rule[1]] = newPropagator(1, model.T)[0]
+		}
+		allowed[rule[1]][from][to] = true
+	}
+
+	tempPropagator := newPropagator(graph.Directions(), model.T)
+	for d := range tempPropagator {
+		label, outgoing := graph.Label(d)
+		rules, ok := allowed[label]
+		if !ok {
+			return nil, WFCError("no rules for edge label: " + label)
+		}
+
+		for t1 := 0; t1 < model.T; t1++ {
+			for t2 := 0; t2 < model.T; t2++ {
+				if outgoing {
+					tempPropagator[d][t1][t2] = rules[t1][t2]
+				} else {
+					tempPropagator[d][t1][t2] = rules[t2][t1]
+				}
+			}
+		}
+	}
+
+	model.Propagator = sparsePropagator(tempPropagator)
+
+	return model, nil
+}
+
+// Clear resets the wave and bans the tiles that no rule supports along an edge of their node, on a graph a tile
+// is often not allowed on one of the edge labels at all
+func (model *GraphModel) Clear() {
+	model.Model.ClearModel()
+	model.BanUnsupported()
+}
+
+func (model *GraphModel) tileIndex(name string) (int, error) {
+	for t, tileName := range model.TileNames {
+		if tileName == name {
+			return t, nil
+		}
+	}
+	return 0, WFCError("tile not recognized: " + name)
+}
+
+// Assignment returns the name of the tile observed at every node, or nil if the model is not fully observed.
+// Masked nodes are left empty.
+func (model *GraphModel) Assignment() []string {
+	if model.Observed == nil {
+		return nil
+	}
+
+	tiles := make([]string, len(model.Observed))
+	for i, t := range model.Observed {
+		if t >= 0 {
+			tiles[i] = model.TileNames[t]
+		}
+	}
+	return tiles
+}
+
+// SelectTile restricts a node to a tile name
+func (model *GraphModel) SelectTile(node int, name string) error {
+	t, err := model.tileIndex(name)
+	if err != nil {
+		return err
+	}
+	return model.SelectAt(node, 0, t)
+}
+
+// BanTile forbids a tile name at a node
+func (model *GraphModel) BanTile(node int, name string) error {
+	t, err := model.tileIndex(name)
+	if err != nil {
+		return err
+	}
+	return model.BanAt(node, 0, t)
+}
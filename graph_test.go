@@ -0,0 +1,29 @@
+package WaveFunctionCollapse
+
+import "testing"
+
+func TestGraphTopologyRejectsDegenerateInputs(t *testing.T) {
+	star := make([]GraphEdge, DefaultMaxSlots+1)
+	for k := range star {
+		star[k] = GraphEdge{From: 0, To: k + 1, Label: "link"}
+	}
+	if _, err := NewGraphTopology(len(star)+1, star, 0); err == nil {
+		t.Error("star wider than DefaultMaxSlots was accepted")
+	}
+	if _, err := NewGraphTopology(len(star)+1, star, len(star)); err != nil {
+		t.Error(err)
+	}
+
+	graph, err := NewGraphTopology(len(star), star[:DefaultMaxSlots-1], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.Directions() != 2*(DefaultMaxSlots-1) {
+		t.Errorf("star has %d directions", graph.Directions())
+	}
+
+	duplicate := []GraphEdge{{From: 0, To: 1, Label: "link"}, {From: 0, To: 1, Label: "link"}}
+	if _, err := NewGraphTopology(2, duplicate, 0); err == nil {
+		t.Error("duplicate edge was accepted")
+	}
+}
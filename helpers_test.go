@@ -0,0 +1,83 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"image/color"
+)
+
+// testSample returns a sample of scattered black dots on white
+func testSample() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if (x*7+y*13)%11 == 0 || (x*3+y*5)%17 == 1 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// testTile draws a 3x3 tile with a path from the center to the sides, in the order of Dx, Dy
+func testTile(sides ...int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	if len(sides) > 0 {
+		img.Set(1, 1, color.Black)
+	}
+	for _, d := range sides {
+		img.Set(1+Dx[d], 1+Dy[d], color.Black)
+	}
+	return img
+}
+
+// testTiles returns a set of path tiles
+func testTiles() ModelInfo {
+	tile := func(name, symmetry string, weight float64, sides ...int) Tile {
+		return Tile{Name: name, Symmetry: symmetry, Weight: weight, images: []image.Image{testTile(sides...)}}
+	}
+
+	return ModelInfo{
+		Size: 3,
+		Tiles: []Tile{
+			tile("empty", "X", 1),
+			tile("line", "I", 2, 1, 3),
+			tile("corner", "L", 1, 0, 1),
+			tile("cross", "X", 0.3, 0, 1, 2, 3),
+			tile("t", "T", 0.5, 0, 1, 2),
+		},
+		Edges: []Edge{
+			{"empty", "empty"}, {"empty", "line"}, {"line 1", "line 1"}, {"line", "line"},
+			{"corner", "corner 1"}, {"corner 1", "empty"}, {"corner", "line"}, {"corner 1", "line 1"},
+			{"cross", "cross"}, {"cross", "line 1"}, {"line 1", "cross"}, {"t", "t 2"}, {"t 1", "empty"},
+			{"t", "line 1"}, {"t 3", "cross"}, {"empty", "t 1"}, {"line", "t 1"},
+		},
+	}
+}
+
+// validAdjacency reports whether every pair of neighbouring observed cells that are not excluded is allowed
+func validAdjacency(model *Model) bool {
+	for i, t := range model.Observed {
+		if t < 0 || model.Excluded(i) {
+			continue
+		}
+
+		for d := 0; d < model.topology.Directions(); d++ {
+			j, ok := model.topology.Neighbor(i, d)
+			if !ok || model.Observed[j] < 0 || model.Excluded(j) {
+				continue
+			}
+			if !contains(model.Propagator[d][t], model.Observed[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
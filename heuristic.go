@@ -0,0 +1,277 @@
+package WaveFunctionCollapse
+
+import (
+	"math"
+	"sort"
+)
+
+// Heuristic selects the next cell to observe. Update is called whenever the wave of a cell changes and Select
+// returns -1 when every cell is decided. Clone returns a heuristic with the same configuration for another model.
+type Heuristic interface {
+	Reset(model *Model)
+	Update(model *Model, i int)
+	Select(model *Model) int
+	Clone() Heuristic
+}
+
+func (model *Model) SetHeuristic(heuristic Heuristic) {
+	model.Heuristic = heuristic
+}
+
+// MinEntropy selects the cell with the lowest entropy by scanning all cells, ties are broken by the noise of the
+// model. It selects the same cells as MinEntropyQueue.
+type MinEntropy struct{}
+
+func (h *MinEntropy) Reset(model *Model)         {}
+func (h *MinEntropy) Update(model *Model, i int) {}
+func (h *MinEntropy) Clone() Heuristic           { return &MinEntropy{} }
+
+func (h *MinEntropy) Select(model *Model) int {
+	min := math.Inf(1)
+	argmin := -1
+
+	for i, amount := range model.SumsOfOnes {
+		if amount <= 1 || model.Excluded(i) {
+			continue
+		}
+
+		entropy := model.Entropies[i]
+		if !(entropy < min) {
+			continue
+		}
+		if !(entropy+model.Noise[i] < min) {
+			continue
+		}
+		min = entropy + model.Noise[i]
+		argmin = i
+	}
+
+	return argmin
+}
+
+// MinEntropyQueue selects the cell with the lowest entropy using an indexed heap that is updated on every ban
+type MinEntropyQueue struct {
+	heap, position []int
+	model          *Model
+}
+
+func (h *MinEntropyQueue) Reset(model *Model) {
+	h.model = model
+	h.heap = h.heap[:0]
+	h.position = make([]int, len(model.SumsOfOnes))
+
+	for i := range h.position {
+		h.position[i] = -1
+		if model.SumsOfOnes[i] > 1 && !model.Excluded(i) {
+			h.position[i] = len(h.heap)
+			h.heap = append(h.heap, i)
+		}
+	}
+
+	for k := len(h.heap)/2 - 1; k >= 0; k-- {
+		h.down(k)
+	}
+}
+
+func (h *MinEntropyQueue) Clone() Heuristic {
+	return &MinEntropyQueue{}
+}
+
+func (h *MinEntropyQueue) Update(model *Model, i int) {
+	if h.position == nil {
+		return
+	}
+
+	k := h.position[i]
+	if k < 0 {
+		//cells only enter the heap again when bans are undone
+		if model.SumsOfOnes[i] > 1 && !model.Excluded(i) {
+			h.position[i] = len(h.heap)
+			h.heap = append(h.heap, i)
+			h.up(len(h.heap) - 1)
+		}
+		return
+	}
+
+	if model.SumsOfOnes[i] <= 1 {
+		last := len(h.heap) - 1
+		h.swap(k, last)
+		h.heap = h.heap[:last]
+		h.position[i] = -1
+		if k < last {
+			h.down(k)
+			h.up(k)
+		}
+		return
+	}
+
+	h.down(k)
+	h.up(k)
+}
+
+func (h *MinEntropyQueue) Select(model *Model) int {
+	if len(h.heap) == 0 {
+		return -1
+	}
+	return h.heap[0]
+}
+
+func (h *MinEntropyQueue) less(a, b int) bool {
+	i, j := h.heap[a], h.heap[b]
+	ki := h.model.Entropies[i] + h.model.Noise[i]
+	kj := h.model.Entropies[j] + h.model.Noise[j]
+	return ki < kj || (ki == kj && i < j)
+}
+
+func (h *MinEntropyQueue) swap(a, b int) {
+	h.heap[a], h.heap[b] = h.heap[b], h.heap[a]
+	h.position[h.heap[a]] = a
+	h.position[h.heap[b]] = b
+}
+
+func (h *MinEntropyQueue) up(k int) {
+	for k > 0 {
+		parent := (k - 1) / 2
+		if !h.less(k, parent) {
+			return
+		}
+		h.swap(k, parent)
+		k = parent
+	}
+}
+
+func (h *MinEntropyQueue) down(k int) {
+	n := len(h.heap)
+	for {
+		min := k
+		if l := 2*k + 1; l < n && h.less(l, min) {
+			min = l
+		}
+		if r := 2*k + 2; r < n && h.less(r, min) {
+			min = r
+		}
+		if min == k {
+			return
+		}
+		h.swap(k, min)
+		k = min
+	}
+}
+
+// MinRemainingValues selects the cell with the least remaining patterns, ties are broken by the noise of the model
+type MinRemainingValues struct{}
+
+func (h *MinRemainingValues) Reset(model *Model)         {}
+func (h *MinRemainingValues) Update(model *Model, i int) {}
+func (h *MinRemainingValues) Clone() Heuristic           { return &MinRemainingValues{} }
+
+func (h *MinRemainingValues) Select(model *Model) int {
+	min := math.Inf(1)
+	argmin := -1
+
+	for i, amount := range model.SumsOfOnes {
+		if amount <= 1 || model.Excluded(i) || !(float64(amount) < min) {
+			continue
+		}
+		value := float64(amount) + model.Noise[i]
+		if value < min {
+			min = value
+			argmin = i
+		}
+	}
+
+	return argmin
+}
+
+// order selects the first undecided cell in a fixed order
+type order struct {
+	order, rank []int
+	cursor      int
+}
+
+func (h *order) set(cells []int) {
+	h.order = cells
+	h.rank = make([]int, len(cells))
+	for r, i := range cells {
+		h.rank[i] = r
+	}
+	h.cursor = 0
+}
+
+func (h *order) Update(model *Model, i int) {
+	//undoing bans can make cells before the cursor undecided again
+	if h.rank != nil && h.rank[i] < h.cursor && model.SumsOfOnes[i] > 1 {
+		h.cursor = h.rank[i]
+	}
+}
+
+func (h *order) Select(model *Model) int {
+	for ; h.cursor < len(h.order); h.cursor++ {
+		i := h.order[h.cursor]
+		if model.SumsOfOnes[i] > 1 && !model.Excluded(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+func identity(n int) []int {
+	cells := make([]int, n)
+	for i := range cells {
+		cells[i] = i
+	}
+	return cells
+}
+
+// Scanline selects the cells row by row
+type Scanline struct {
+	order
+}
+
+func (h *Scanline) Reset(model *Model) {
+	h.set(identity(len(model.SumsOfOnes)))
+}
+
+func (h *Scanline) Clone() Heuristic {
+	return &Scanline{}
+}
+
+// RandomOrder selects the cells in a random order, the order is derived from the noise of the model
+type RandomOrder struct {
+	order
+}
+
+func (h *RandomOrder) Reset(model *Model) {
+	cells := identity(len(model.SumsOfOnes))
+	sort.SliceStable(cells, func(a, b int) bool {
+		return model.Noise[cells[a]] < model.Noise[cells[b]]
+	})
+	h.set(cells)
+}
+
+func (h *RandomOrder) Clone() Heuristic {
+	return &RandomOrder{}
+}
+
+// Growth selects the cells in order of their distance to a seed point, growing the output outward
+type Growth struct {
+	order
+	X, Y int
+}
+
+func (h *Growth) Reset(model *Model) {
+	distance := func(i int) int {
+		dx, dy := i%model.Fmx-h.X, i/model.Fmx-h.Y
+		return dx*dx + dy*dy
+	}
+
+	cells := identity(len(model.SumsOfOnes))
+	sort.SliceStable(cells, func(a, b int) bool {
+		return distance(cells[a]) < distance(cells[b])
+	})
+	h.set(cells)
+}
+
+func (h *Growth) Clone() Heuristic {
+	return &Growth{X: h.X, Y: h.Y}
+}
@@ -0,0 +1,50 @@
+package WaveFunctionCollapse
+
+import "testing"
+
+func TestMinEntropyQueueMatchesScan(t *testing.T) {
+	sample := testSample()
+	compared := 0
+	for seed := int64(1); seed <= 5; seed++ {
+		scan := NewOverlappingModel(sample, 3, 48, 48, true, true, 8, 0, seed)
+		scan.SetHeuristic(&MinEntropy{})
+		queue := NewOverlappingModel(sample, 3, 48, 48, true, true, 8, 0, seed)
+		queue.SetHeuristic(&MinEntropyQueue{})
+
+		if scan.Run(0) != queue.Run(0) {
+			t.Fatalf("seed %d: runs ended differently", seed)
+		}
+		if scan.Observed == nil {
+			continue
+		}
+		compared++
+		for i := range scan.Observed {
+			if scan.Observed[i] != queue.Observed[i] {
+				t.Fatalf("seed %d: cell %d observed %d with the scan and %d with the queue", seed, i,
+					scan.Observed[i], queue.Observed[i])
+			}
+		}
+	}
+
+	if compared == 0 {
+		t.Fatal("no run was fully observed")
+	}
+}
+
+func benchmarkHeuristic(b *testing.B, heuristic func() Heuristic) {
+	sample := testSample()
+	b.ReportAllocs()
+	for k := 0; k < b.N; k++ {
+		model := NewOverlappingModel(sample, 3, 256, 256, true, true, 8, 0, int64(k))
+		model.SetHeuristic(heuristic())
+		model.Run(0)
+	}
+}
+
+func BenchmarkMinEntropyScan256(b *testing.B) {
+	benchmarkHeuristic(b, func() Heuristic { return &MinEntropy{} })
+}
+
+func BenchmarkMinEntropyQueue256(b *testing.B) {
+	benchmarkHeuristic(b, func() Heuristic { return &MinEntropyQueue{} })
+}
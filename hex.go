@@ -0,0 +1,361 @@
+package WaveFunctionCollapse
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// HexSides names the six sides of a hex in direction order
+var HexSides = [6]string{"e", "ne", "nw", "w", "sw", "se"}
+
+var (
+	hexEven = [6][2]int{{1, 0}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}, {0, 1}}
+	hexOdd  = [6][2]int{{1, 0}, {1, -1}, {0, -1}, {-1, 0}, {0, 1}, {1, 1}}
+)
+
+// HexGrid connects the cells of a grid of pointy-top hexes in odd-row offset coordinates, the odd rows are shifted
+// half a hex to the right. The directions are ordered counterclockwise starting east. A periodic grid needs an even
+// height to wrap consistently.
+type HexGrid struct {
+	Width, Height int
+	Periodic      bool
+}
+
+func (grid HexGrid) Size() int {
+	return grid.Width * grid.Height
+}
+
+func (grid HexGrid) Directions() int {
+	return 6
+}
+
+func (grid HexGrid) Opposite(d int) int {
+	return (d + 3) % 6
+}
+
+func (grid HexGrid) Neighbor(i, d int) (int, bool) {
+	x, y := i%grid.Width, i/grid.Width
+
+	offset := hexEven[d]
+	if y%2 == 1 {
+		offset = hexOdd[d]
+	}
+	x, y = x+offset[0], y+offset[1]
+
+	if grid.Periodic {
+		x = (x + grid.Width) % grid.Width
+		y = (y + grid.Height) % grid.Height
+	} else if x < 0 || y < 0 || x >= grid.Width || y >= grid.Height {
+		return 0, false
+	}
+
+	return x + y*grid.Width, true
+}
+
+func (grid HexGrid) Boundary(i int) bool {
+	return false
+}
+
+// HexSymmetryFunc returns the 60 degree counterclockwise rotation and the reflection in the horizontal axis of
+// the orientations of a hex symmetry class. Symmetric classes must be drawn symmetric in the horizontal axis.
+//
+//	X: invariant under every rotation and reflection, 1 orientation
+//	Y: invariant under 120 degree rotations and reflection, 2 orientations
+//	I: invariant under 180 degree rotations and reflection, 3 orientations
+//	T: invariant under reflection only, 6 orientations
+//	L: no symmetry, 12 orientations
+func HexSymmetryFunc(symmetry string) (a, b func(int) int, cardinality int) {
+	period, chiral := 1, false
+	switch symmetry {
+	case "Y":
+		period = 2
+	case "I":
+		period = 3
+	case "T":
+		period = 6
+	case "L":
+		period, chiral = 6, true
+	}
+
+	cardinality = period
+	if chiral {
+		cardinality *= 2
+	}
+
+	a = func(i int) int {
+		return (i%period+1)%period + i/period*period
+	}
+	b = func(i int) int {
+		k, r := i%period, i/period
+		if chiral {
+			r = 1 - r
+		}
+		return (period-k)%period + r*period
+	}
+	return
+}
+
+type HexEdge [3]string
+
+// ParseHexEdge parses an edge of the form ["tile cardinal", "side", "tile cardinal"], the side of the first tile
+// touches the second tile
+func ParseHexEdge(edge HexEdge) (leftname string, leftcardinal, side int, rightname string, rightcardinal int, err error) {
+	leftname, leftcardinal, rightname, rightcardinal = ParseEdge(Edge{edge[0], edge[2]})
+
+	side = -1
+	for d, name := range HexSides {
+		if strings.EqualFold(name, edge[1]) {
+			side = d
+		}
+	}
+	if side < 0 {
+		err = WFCError("hex side not recognized: " + edge[1])
+	}
+	return
+}
+
+type HexModelInfo struct {
+	Tiles []Tile    `json:"tiles"`
+	Edges []HexEdge `json:"edges"`
+}
+
+func (info *HexModelInfo) Initialize() error {
+	for t := range info.Tiles {
+		if err := info.Tiles[t].LoadFiles(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HexModel is a tiled model on a hex grid. The tile images contain a pointy-top hex and are transparent outside
+// of it, the rows of the output overlap by a quarter of the tile height.
+type HexModel struct {
+	*Model
+
+	TileWidth, TileHeight int
+	Tiles                 [][]color.Color
+	TileNames             []string
+}
+
+func NewHexModel(info HexModelInfo, width, height int, periodic bool, seed int64) (model *HexModel, err error) {
+	if periodic && height%2 == 1 {
+		return nil, WFCError("periodic hex grid needs an even height")
+	}
+	if len(info.Tiles) == 0 || len(info.Tiles[0].images) == 0 {
+		return nil, WFCError("no tiles")
+	}
+
+	bounds := info.Tiles[0].images[0].Bounds()
+	model = &HexModel{
+		Model: &Model{
+			Rules:      &Rules{Topology: HexGrid{Width: width, Height: height, Periodic: periodic}},
+			Fmx:        width,
+			Fmy:        height,
+			Periodic:   periodic,
+			RandomSeed: seed,
+		},
+		TileWidth:  bounds.Dx(),
+		TileHeight: bounds.Dy(),
+	}
+
+	model.Tiles = make([][]color.Color, 0)
+	model.TileNames = make([]string, 0)
+	model.Weights = make([]float64, 0)
+
+	action := make([][12]int, 0)
+	firstOccurrence := make(map[string]int)
+	cardinalities := make(map[string]int)
+
+	for _, tile := range info.Tiles {
+		a, b, cardinality := HexSymmetryFunc(tile.Symmetry)
+		T := len(action)
+		firstOccurrence[tile.Name] = T
+		cardinalities[tile.Name] = cardinality
+
+		files := 1
+		if tile.Unique {
+			files = cardinality
+		}
+		if len(tile.images) < files {
+			return nil, WFCError(fmt.Sprintf("tile %s needs %d images", tile.Name, files))
+		}
+
+		for t := 0; t < cardinality; t++ {
+			var symmetryMap [12]int
+			rotated := t
+			for k := 0; k < 6; k++ {
+				symmetryMap[k] = T + rotated
+				symmetryMap[6+k] = T + b(rotated)
+				rotated = a(rotated)
+			}
+			action = append(action, symmetryMap)
+		}
+
+		for t := 0; t < cardinality; t++ {
+			var tileData []color.Color
+			if tile.Unique {
+				tileData = model.Tile(tile.images[t].At)
+			} else if t == 0 {
+				tileData = model.Tile(tile.images[0].At)
+			} else if a(t-1) == t {
+				tileData = model.Rotate(model.Tiles[T+t-1])
+			} else {
+				//the reflected orientations of chiral tiles follow the rotations
+				tileData = model.Reflect(model.Tiles[T+b(t)])
+			}
+
+			model.Tiles = append(model.Tiles, tileData)
+			model.TileNames = append(model.TileNames, fmt.Sprintf("%s %d", tile.Name, t))
+			model.Weights = append(model.Weights, tile.Weight)
+		}
+	}
+
+	model.T = len(action)
+	tempPropagator := newPropagator(6, model.T)
+
+	for _, edge := range info.Edges {
+		leftName, leftCardinal, side, rightName, rightCardinal, err := ParseHexEdge(edge)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range []string{leftName, rightName} {
+			if _, ok := firstOccurrence[name]; !ok {
+				return nil, WFCError("tile not recognized: " + name)
+			}
+		}
+		if leftCardinal < 0 || leftCardinal >= cardinalities[leftName] ||
+			rightCardinal < 0 || rightCardinal >= cardinalities[rightName] {
+			return nil, WFCError("cardinal out of range")
+		}
+
+		l := firstOccurrence[leftName] + leftCardinal
+		r := firstOccurrence[rightName] + rightCardinal
+
+		//every rotation and reflection of an allowed pair is allowed as well
+		for k := 0; k < 12; k++ {
+			d := (side + k) % 6
+			if k >= 6 {
+				d = (6 - (side+k)%6) % 6
+			}
+
+			tempPropagator[d][action[l][k]][action[r][k]] = true
+			tempPropagator[(d+3)%6][action[r][k]][action[l][k]] = true
+		}
+	}
+
+	model.Propagator = sparsePropagator(tempPropagator)
+
+	return model, nil
+}
+
+func (model *HexModel) Tile(f func(int, int) color.Color) (result []color.Color) {
+	result = make([]color.Color, model.TileWidth*model.TileHeight)
+	for y := 0; y < model.TileHeight; y++ {
+		for x := 0; x < model.TileWidth; x++ {
+			result[x+y*model.TileWidth] = f(x, y)
+		}
+	}
+	return
+}
+
+// Rotate rotates a hex tile 60 degrees counterclockwise, the tile is scaled to a regular hex while rotating
+func (model *HexModel) Rotate(tile []color.Color) []color.Color {
+	w, h := float64(model.TileWidth), float64(model.TileHeight)
+	cx, cy := (w-1)/2, (h-1)/2
+	scale := w * 2 / math.Sqrt(3) / h
+	sin, cos := math.Sincos(math.Pi / 3)
+
+	return model.Tile(func(x int, y int) color.Color {
+		//rotate back to find the source pixel, y points down
+		u, v := float64(x)-cx, (cy-float64(y))*scale
+		su, sv := u*cos+v*sin, -u*sin+v*cos
+
+		sx, sy := int(math.Floor(su+cx+0.5)), int(math.Floor(cy-sv/scale+0.5))
+		if sx < 0 || sy < 0 || sx >= model.TileWidth || sy >= model.TileHeight {
+			return color.Transparent
+		}
+		return tile[sx+sy*model.TileWidth]
+	})
+}
+
+// Reflect reflects a hex tile in its horizontal axis
+func (model *HexModel) Reflect(tile []color.Color) []color.Color {
+	return model.Tile(func(x int, y int) color.Color {
+		return tile[x+(model.TileHeight-1-y)*model.TileWidth]
+	})
+}
+
+func (model *HexModel) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (model *HexModel) rowHeight() int {
+	return model.TileHeight * 3 / 4
+}
+
+func (model *HexModel) Bounds() image.Rectangle {
+	return image.Rect(0, 0, model.Fmx*model.TileWidth+model.TileWidth/2, (model.Fmy-1)*model.rowHeight()+model.TileHeight)
+}
+
+// At draws the hex covering the pixel, rows further down are drawn on top of the rows above
+func (model *HexModel) At(x, y int) color.Color {
+	row := y / model.rowHeight()
+
+	for ty := row; ty >= row-1 && ty >= 0; ty-- {
+		if ty >= model.Fmy {
+			continue
+		}
+
+		px := x
+		if ty%2 == 1 {
+			px -= model.TileWidth / 2
+		}
+		if px < 0 {
+			continue
+		}
+
+		tx := px / model.TileWidth
+		xt, yt := px%model.TileWidth, y-ty*model.rowHeight()
+		if tx >= model.Fmx || yt >= model.TileHeight {
+			continue
+		}
+
+		if c := model.cellColor(tx+ty*model.Fmx, xt+yt*model.TileWidth); c.A > 0 {
+			return c
+		}
+	}
+
+	return model.ColorModel().Convert(color.Transparent)
+}
+
+func (model *HexModel) cellColor(i, p int) color.RGBA {
+	if model.Masked(i) {
+		return color.RGBA{}
+	} else if model.Observed != nil {
+		return model.ColorModel().Convert(model.Tiles[model.Observed[i]][p]).(color.RGBA)
+	}
+
+	var r, g, b, a, sum float64
+	for t := 0; t < model.T; t++ {
+		if model.Possible(i, t) {
+			cr, cg, cb, ca := model.Tiles[t][p].RGBA()
+			w := model.Weights[t]
+			r, g, b, a, sum = r+float64(cr)*w, g+float64(cg)*w, b+float64(cb)*w, a+float64(ca)*w, sum+w
+		}
+	}
+
+	if sum == 0 {
+		return color.RGBA{}
+	}
+
+	return model.ColorModel().Convert(color.RGBA64{
+		R: uint16(r / sum),
+		G: uint16(g / sum),
+		B: uint16(b / sum),
+		A: uint16(a / sum),
+	}).(color.RGBA)
+}
@@ -0,0 +1,37 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"testing"
+)
+
+func TestHexModelPeriodicOddHeight(t *testing.T) {
+	_, err := NewHexModel(HexModelInfo{}, 8, 7, true, 1)
+	if err != WFCError("periodic hex grid needs an even height") {
+		t.Fatalf("periodic hex grid with an odd height gave %v", err)
+	}
+}
+
+func TestHexModelInvalidInfo(t *testing.T) {
+	tile := func(name, symmetry string, unique bool) Tile {
+		return Tile{Name: name, Symmetry: symmetry, Unique: unique, Weight: 1, images: []image.Image{testTile()}}
+	}
+
+	infos := map[string]HexModelInfo{
+		"unique tile with one image": {Tiles: []Tile{tile("a", "L", true)}},
+		"cardinal out of range": {Tiles: []Tile{tile("a", "I", false)},
+			Edges: []HexEdge{{"a 3", "e", "a"}}},
+		"unknown tile": {Tiles: []Tile{tile("a", "X", false)}, Edges: []HexEdge{{"a", "e", "b"}}},
+	}
+
+	for name, info := range infos {
+		if _, err := NewHexModel(info, 4, 4, false, 1); err == nil {
+			t.Errorf("%s was accepted", name)
+		}
+	}
+
+	valid := HexModelInfo{Tiles: []Tile{tile("a", "I", false)}, Edges: []HexEdge{{"a 2", "e", "a"}}}
+	if _, err := NewHexModel(valid, 4, 4, false, 1); err != nil {
+		t.Error(err)
+	}
+}
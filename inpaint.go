@@ -0,0 +1,85 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"image/color"
+)
+
+// Inpaint keeps the pixels of a target image of the size of the output and only generates the holes, the pixels
+// where the mask is neither black nor transparent. Without a mask the transparent pixels of the target are the
+// holes. The known pixels restrict the patterns of the cells that cover them when the model is cleared.
+func (model *OverlappingModel) Inpaint(target, mask image.Image) error {
+	bounds := target.Bounds()
+	if bounds.Dx() != model.Fmx || bounds.Dy() != model.Fmy {
+		return WFCError("target size does not match the output")
+	}
+
+	var holes []float64
+	if mask != nil {
+		var err error
+		holes, err = imageField(mask, model.Fmx, model.Fmy, func(c color.Color) float64 {
+			if r, g, b, a := c.RGBA(); a > 0 && r+g+b > 0 {
+				return 1
+			}
+			return 0
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	known := make([]int, model.Fmx*model.Fmy)
+	for y := 0; y < model.Fmy; y++ {
+		for x := 0; x < model.Fmx; x++ {
+			p := x + y*model.Fmx
+			c := target.At(bounds.Min.X+x, bounds.Min.Y+y)
+
+			known[p] = -1
+			if holes != nil && holes[p] > 0 || holes == nil && isTransparent(c) {
+				continue
+			}
+
+			for k, ref := range model.Colors {
+				if ColorEquals(c, ref) {
+					known[p] = k
+					break
+				}
+			}
+			if known[p] < 0 {
+				return WFCError("target color does not occur in the sample")
+			}
+		}
+	}
+
+	model.Known = known
+	return nil
+}
+
+func isTransparent(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	return a == 0
+}
+
+// applyKnown bans the patterns that disagree with a known pixel they cover
+func (model *OverlappingModel) applyKnown() {
+	for i := range model.SumsOfOnes {
+		if model.Excluded(i) {
+			continue
+		}
+
+		x, y := i%model.Fmx, i/model.Fmx
+		for t := 0; t < model.T; t++ {
+			if !model.Possible(i, t) {
+				continue
+			}
+
+			for k, c := range model.Patterns[t] {
+				px, py := (x+k%model.N)%model.Fmx, (y+k/model.N)%model.Fmy
+				if known := model.Known[px+py*model.Fmx]; known >= 0 && known != int(c) {
+					model.Ban(i, t)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestInpaintKeepsKnownPixels(t *testing.T) {
+	source := NewOverlappingModel(testSample(), 3, 16, 16, true, true, 8, 0, 1)
+	if !source.Run(0) {
+		t.Fatal("source contradicted")
+	}
+
+	//the output of the source with a transparent hole
+	target := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	hole := image.Rect(5, 5, 11, 11)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if !(image.Point{X: x, Y: y}).In(hole) {
+				target.Set(x, y, source.At(x, y))
+			}
+		}
+	}
+
+	model := NewOverlappingModel(testSample(), 3, 16, 16, true, true, 8, 0, 2)
+	model.SetBacktracking(1000, 0)
+	if err := model.Inpaint(image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err == nil {
+		t.Error("target of the wrong size was accepted")
+	}
+	if err := model.Inpaint(target, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !model.Run(0) {
+		t.Fatal("inpainting contradicted")
+	}
+
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if (image.Point{X: x, Y: y}).In(hole) {
+				continue
+			}
+			if c := model.ObservedColor(x, y); !ColorEquals(c, target.At(x, y)) {
+				t.Errorf("known pixel %d, %d changed to %v", x, y, color.RGBAModel.Convert(c))
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package WaveFunctionCollapse
+
+// Listener receives the internal events of a model. Cells are identified by their index in the wave, a contradiction
+// of a global constraint that no cell can meet is reported at Unsatisfiable.
+type Listener interface {
+	OnObserve(i, t int, entropy float64)
+	OnBan(i, t int)
+	OnPropagateStart()
+	OnPropagateEnd()
+	OnContradiction(i int)
+}
+
+// NopListener implements Listener without doing anything, embed it to implement only the events of interest.
+type NopListener struct{}
+
+func (NopListener) OnObserve(i, t int, entropy float64) {}
+func (NopListener) OnBan(i, t int)                      {}
+func (NopListener) OnPropagateStart()                   {}
+func (NopListener) OnPropagateEnd()                     {}
+func (NopListener) OnContradiction(i int)               {}
+
+func (model *Model) AddListener(listener Listener) {
+	model.Listeners = append(model.Listeners, listener)
+}
+
+func (model *Model) RemoveListener(listener Listener) {
+	for k, l := range model.Listeners {
+		if l == listener {
+			model.Listeners = append(model.Listeners[:k], model.Listeners[k+1:]...)
+			return
+		}
+	}
+}
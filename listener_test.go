@@ -0,0 +1,68 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"testing"
+)
+
+type countingListener struct {
+	observed                           map[int]int
+	bans, starts, ends, contradictions int
+	contradiction                      int
+}
+
+func (l *countingListener) OnObserve(i, t int, entropy float64) {
+	l.observed[i] = t
+}
+
+func (l *countingListener) OnBan(i, t int) {
+	l.bans++
+}
+
+func (l *countingListener) OnPropagateStart() {
+	l.starts++
+}
+
+func (l *countingListener) OnPropagateEnd() {
+	l.ends++
+}
+
+func (l *countingListener) OnContradiction(i int) {
+	l.contradictions++
+	l.contradiction = i
+}
+
+func TestListenerEvents(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		model := NewTiledModel(testTiles(), 16, 16, true, false, seed)
+		listener := &countingListener{observed: make(map[int]int)}
+		model.AddListener(listener)
+
+		result := model.Solve(context.Background(), 0)
+		if len(listener.observed) != result.Observations || listener.bans != result.Bans {
+			t.Errorf("seed %d: %d observations and %d bans reported, the result has %d and %d", seed,
+				len(listener.observed), listener.bans, result.Observations, result.Bans)
+		}
+		if listener.starts != result.Propagations || listener.ends != listener.starts {
+			t.Errorf("seed %d: %d propagation starts and %d ends for %d propagations", seed, listener.starts,
+				listener.ends, result.Propagations)
+		}
+
+		switch result.Status {
+		case StatusSuccess:
+			if listener.contradictions != 0 {
+				t.Errorf("seed %d: contradiction reported on success", seed)
+			}
+			for i, p := range listener.observed {
+				if model.Observed[i] != p {
+					t.Errorf("seed %d: cell %d observed as %d holds %d", seed, i, p, model.Observed[i])
+				}
+			}
+		case StatusContradiction:
+			if listener.contradictions != 1 || listener.contradiction != result.Contradiction.Cell {
+				t.Errorf("seed %d: contradiction at %d reported %d times, the result has %d", seed,
+					listener.contradiction, listener.contradictions, result.Contradiction.Cell)
+			}
+		}
+	}
+}
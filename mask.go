@@ -0,0 +1,41 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"image/color"
+)
+
+// Masked reports whether cell i is masked out, masked cells are not generated and hold -1 in Observed
+func (model *Model) Masked(i int) bool {
+	return model.Mask != nil && !model.Mask[i]
+}
+
+// SetMask restricts the generation to the cells where the mask is true, nil generates every cell
+func (model *Model) SetMask(mask []bool) error {
+	if mask != nil && len(mask) != model.grid().Size() {
+		return WFCError("mask size does not match the model")
+	}
+	model.Mask = mask
+	return nil
+}
+
+// SetMaskImage scales an image to the cells of the output and generates the cells whose pixel is neither black
+// nor transparent
+func (model *Model) SetMaskImage(img image.Image) error {
+	field, err := imageField(img, model.Fmx, model.Fmy, func(c color.Color) float64 {
+		r, g, b, a := c.RGBA()
+		if a > 0 && r+g+b > 0 {
+			return 1
+		}
+		return 0
+	})
+	if err != nil {
+		return err
+	}
+
+	mask := make([]bool, len(field))
+	for i, on := range field {
+		mask[i] = on > 0
+	}
+	return model.SetMask(mask)
+}
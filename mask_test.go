@@ -0,0 +1,40 @@
+package WaveFunctionCollapse
+
+import "testing"
+
+func TestMaskedCells(t *testing.T) {
+	model := NewTiledModel(testTiles(), 12, 12, false, false, 1)
+	model.SetBacktracking(1000, 0)
+
+	if err := model.SetMask(make([]bool, 10)); err == nil {
+		t.Error("mask of the wrong size was accepted")
+	}
+
+	mask := make([]bool, 12*12)
+	for i := range mask {
+		x, y := i%12, i/12
+		mask[i] = x < 4 || y < 4 || x >= 8 || y >= 8
+	}
+	if err := model.SetMask(mask); err != nil {
+		t.Fatal(err)
+	}
+	if !model.Run(0) {
+		t.Fatal("masked model contradicted")
+	}
+
+	for i, p := range model.Observed {
+		if mask[i] != (p >= 0) {
+			t.Errorf("cell %d with mask %v holds %d", i, mask[i], p)
+		}
+	}
+
+	size := model.TileSize
+	for y := 0; y < 12*size; y++ {
+		for x := 0; x < 12*size; x++ {
+			_, _, _, a := model.At(x, y).RGBA()
+			if masked := !mask[x/size+y/size*12]; masked != (a == 0) {
+				t.Fatalf("pixel %d, %d of a cell with mask %v has alpha %d", x, y, !masked, a)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package WaveFunctionCollapse
 
 import (
+	"context"
 	"image"
 	"math"
 	"math/rand"
@@ -18,94 +19,296 @@ var (
 	Dx       = [4]int{-1, 0, 1, 0}
 	Dy       = [4]int{0, 1, 0, -1}
 	Opposite = [4]int{2, 3, 0, 1}
+	Sides    = [4]string{"left", "bottom", "right", "top"}
+)
+
+const (
+	ErrContradiction WFCError = "contradiction"
+	ErrCancelled     WFCError = "cancelled"
+	ErrTimeout       WFCError = "timed out"
+	ErrLimitReached  WFCError = "iteration limit reached"
 )
 
 type IntTuple struct {
 	A, B int
 }
 
+// Solver is a model that can be cloned and solved from a seed, as used by SolveParallel
+type Solver interface {
+	Solve(ctx context.Context, limit int) Result
+	Seed() int64
+	SetSeed(seed int64)
+	Clone() Solver
+}
+
 type WFCModel interface {
 	image.Image
+	Solver
 	Run(limit int) bool
+	RunContext(ctx context.Context, limit int) error
 }
 
 type Model struct {
-	Wave                                                 [][]bool
-	Propagator                                           [4][][]int
-	Compatible                                           [][][4]int
-	Observed                                             []int
-	Stack                                                []IntTuple
-	StackSize                                            int
-	Fmx, Fmy, T                                          int
-	Periodic                                             bool
-	Weights                                              []float64
-	WeightLogWeights                                     []float64
-	SumsOfOnes                                           []int
-	SumOfWeights, SumOfWeightLogWeights, StartingEntropy float64
-	SumsOfWeights, SumsOfWeightLogWeights, Entropies     []float64
-
+	*Rules
+
+	Wave                                             Bitset
+	Compatible                                       []int32
+	Observed                                         []int
+	Stack                                            []IntTuple
+	Fmx, Fmy                                         int
+	Periodic                                         bool
+	SumsOfOnes                                       []int
+	SumsOfWeights, SumsOfWeightLogWeights, Entropies []float64
+	Noise                                            []float64
+	RandomSeed                                       int64
+	Backtracking                                     bool
+	BacktrackBudget, BacktrackDepth                  int
+	Constraints                                      []CellConstraint
+	Mask                                             []bool
+	WeightMaps                                       []func(i int) float64 `json:"-"`
+
+	Globals   []GlobalConstraint           `json:"-"`
+	Source    rand.Source                  `json:"-"`
+	NewSource func(seed int64) rand.Source `json:"-"`
+	Heuristic Heuristic                    `json:"-"`
+	Listeners []Listener                   `json:"-"`
+	ImplClear func()                       `json:"-"`
+
+	// Deprecated: OnBoundary is only used by models whose rules have no Topology, set a Topology instead.
 	OnBoundary func(x, y int) bool `json:"-"`
-	ImplClear  func()              `json:"-"`
+
+	topology      Topology
+	words         int
+	directions    int
+	random        *rand.Rand
+	counter       countingSource
+	contradiction int
+	trail         []IntTuple
+	decisions     []decision
+	backtracks    int
+	cloneErr      error
+
+	observations, propagations, bans int
 }
 
 func (model *Model) Run(limit int) bool {
+	err := model.RunContext(context.Background(), limit)
+	return err == nil || err == ErrLimitReached
+}
+
+// RunContext runs the model until it is fully observed, a contradiction is found, the iteration limit is reached or
+// the context is done. The wave is left as is when the run is interrupted.
+func (model *Model) RunContext(ctx context.Context, limit int) error {
+	if err := model.prepare(); err != nil {
+		return err
+	}
+
+	if !model.applyConstraints() {
+		for _, l := range model.Listeners {
+			l.OnContradiction(model.contradiction)
+		}
+		return ErrContradiction
+	}
+
+	return model.Resume(ctx, limit)
+}
+
+// Resume continues observing the model from its current state without clearing it, e.g. after an interrupted run
+// or a restored snapshot.
+func (model *Model) Resume(ctx context.Context, limit int) error {
+	if len(model.Stack) > 0 {
+		if err := model.propagate(ctx); err != nil {
+			return err
+		}
+	}
+
+	for l := 0; l < limit || limit == 0; l++ {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+
+		result := model.Observe()
+		if result == ModelFalse && model.Backtracking {
+			ok, err := model.backtrack(ctx)
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+		}
+		if result == ModelTrue {
+			return nil
+		} else if result == ModelFalse {
+			return ErrContradiction
+		}
+
+		if err := model.propagate(ctx); err != nil {
+			return err
+		}
+	}
+
+	return ErrLimitReached
+}
+
+func (model *Model) prepare() error {
+	if model.cloneErr != nil {
+		return model.cloneErr
+	}
 	if model.Wave == nil {
-		model.Init()
+		if err := model.Init(); err != nil {
+			return err
+		}
 	}
+	model.topology = model.grid()
+
+	model.reseed()
 
 	if model.ImplClear != nil {
 		model.ImplClear()
 	} else {
 		model.ClearModel()
 	}
+	return nil
+}
 
-	for l := 0; l < limit || limit == 0; l++ {
-		result := model.Observe()
-		if result != ModelNull {
-			return result == ModelTrue
-		}
-		model.Propagate()
+func contextError(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return ErrTimeout
+	default:
+		return ErrCancelled
 	}
+}
 
-	return true
+// Seed returns the seed used by the next (or last) call to Run
+func (model *Model) Seed() int64 {
+	return model.RandomSeed
 }
 
-func (model *Model) Init() {
-	waveLength := model.Fmx * model.Fmy
-	model.Wave = make([][]bool, waveLength)
-	model.Compatible = make([][][4]int, waveLength)
+func (model *Model) SetSeed(seed int64) {
+	model.RandomSeed = seed
+}
+
+// SetSource replaces the random source of the model, the source is reseeded with the model seed on every Run. A
+// source of another kind than rand.NewSource can not be cloned, use SetSourceFactory to solve the model in parallel.
+func (model *Model) SetSource(source rand.Source) {
+	model.Source = source
+	model.NewSource = nil
+}
+
+// SetSourceFactory makes the model and its clones create their random source with f from the model seed
+func (model *Model) SetSourceFactory(f func(seed int64) rand.Source) {
+	model.Source = nil
+	model.NewSource = f
+}
+
+// Random returns the random number generator of the current run
+func (model *Model) Random() *rand.Rand {
+	return model.random
+}
 
-	for i := range model.Wave {
-		model.Wave[i] = make([]bool, model.T)
-		model.Compatible[i] = make([][4]int, model.T)
+// Reseed restarts the random number generator of the current run with a new seed, which allows multiple
+// continuations to be branched from the same state.
+func (model *Model) Reseed(seed int64) {
+	model.RandomSeed = seed
+	model.reseed()
+}
+
+func (model *Model) reseed() {
+	if model.Source == nil && model.NewSource != nil {
+		model.Source = model.NewSource(model.RandomSeed)
+	} else if model.Source == nil {
+		model.Source = rand.NewSource(model.RandomSeed)
+	} else {
+		model.Source.Seed(model.RandomSeed)
 	}
+	model.counter = countingSource{source: model.Source}
+	model.random = rand.New(&model.counter)
+}
+
+// countingSource counts the values drawn from a source, which is enough to restore its state from the seed
+type countingSource struct {
+	source rand.Source
+	draws  uint64
+}
+
+func (source *countingSource) Int63() int64 {
+	source.draws++
+	return source.source.Int63()
+}
 
-	model.WeightLogWeights = make([]float64, model.T)
+func (source *countingSource) Seed(seed int64) {
+	source.source.Seed(seed)
+	source.draws = 0
+}
 
-	for t := range model.WeightLogWeights {
-		model.WeightLogWeights[t] = model.Weights[t] * math.Log10(model.Weights[t])
-		model.SumOfWeights += model.Weights[t]
-		model.SumOfWeightLogWeights += model.WeightLogWeights[t]
+// Init allocates the wave for the rules and the cells of the model, the rules are compiled on first use
+func (model *Model) Init() error {
+	if model.Rules == nil {
+		return WFCError("model has no rules")
 	}
 
-	model.StartingEntropy = math.Log10(model.SumOfWeights) - model.SumOfWeightLogWeights/model.SumOfWeights
+	model.topology = model.grid()
+	model.Compile()
+
+	waveLength := model.topology.Size()
+
+	//every cell starts on a word boundary of the wave, the compatibility counts of a cell are stored contiguously
+	model.words = (model.T + 63) / 64
+	model.directions = model.topology.Directions()
+	model.Wave = make(Bitset, waveLength*model.words)
+	model.Compatible = make([]int32, waveLength*model.T*model.directions)
 
 	model.SumsOfOnes = make([]int, waveLength)
 	model.SumsOfWeights = make([]float64, waveLength)
 	model.SumsOfWeightLogWeights = make([]float64, waveLength)
 	model.Entropies = make([]float64, waveLength)
+	model.Noise = make([]float64, waveLength)
+
+	model.Stack = make([]IntTuple, 0, waveLength)
+	return nil
+}
 
-	model.Stack = make([]IntTuple, waveLength*model.T)
+// grid returns the topology of the rules, without one the cells form a Fmx x Fmy grid that is periodic when the
+// model is and whose boundary is given by OnBoundary
+func (model *Model) grid() Topology {
+	if model.Rules != nil && model.Topology != nil {
+		return model.Topology
+	}
+
+	grid := SquareGrid{Width: model.Fmx, Height: model.Fmy, Periodic: model.Periodic}
+	if model.OnBoundary == nil {
+		return grid
+	}
+	return boundaryGrid{SquareGrid: grid, onBoundary: model.OnBoundary}
+}
+
+// Possible reports whether pattern t is still allowed in cell i
+func (model *Model) Possible(i, t int) bool {
+	return model.Wave.Get(i*model.words*64 + t)
+}
+
+// Excluded reports whether cell i is left out of the observation
+func (model *Model) Excluded(i int) bool {
+	return model.topology.Boundary(i) || model.Masked(i)
+}
+
+func (model *Model) cellWave(i int) Bitset {
+	return model.Wave[i*model.words : (i+1)*model.words]
 }
 
 func (model *Model) ClearModel() {
 	numWeights := len(model.Weights)
 
-	for i := range model.Wave {
+	for i := range model.SumsOfOnes {
+		model.cellWave(i).Fill(model.T)
 		for t := 0; t < model.T; t++ {
-			model.Wave[i][t] = true
-			for d := 0; d < 4; d++ {
-				model.Compatible[i][t][d] = len(model.Propagator[Opposite[d]][t])
+			compatible := model.Compatible[(i*model.T+t)*model.directions:]
+			for d := 0; d < model.directions; d++ {
+				compatible[d] = int32(len(model.Propagator[model.topology.Opposite(d)][t]))
 			}
 		}
 
@@ -113,63 +316,93 @@ func (model *Model) ClearModel() {
 		model.SumsOfWeights[i] = model.SumOfWeights
 		model.SumsOfWeightLogWeights[i] = model.SumOfWeightLogWeights
 		model.Entropies[i] = model.StartingEntropy
-	}
-}
-
-func (model *Model) Observe() ModelResult {
-	min := 1E+3
-	argmin := -1
+		model.Noise[i] = 1e-6 * model.random.Float64()
 
-	for i := range model.Wave {
-		if model.OnBoundary(i%model.Fmx, i/model.Fmx) {
-			continue
+		if model.WeightMaps != nil {
+			model.SumsOfWeights[i], model.SumsOfWeightLogWeights[i] = 0, 0
+			for t := 0; t < model.T; t++ {
+				w, wlw := model.weight(i, t)
+				model.SumsOfWeights[i] += w
+				model.SumsOfWeightLogWeights[i] += wlw
+			}
+			model.Entropies[i] = entropy(model.SumsOfWeights[i], model.SumsOfWeightLogWeights[i])
 		}
+	}
 
-		amount := model.SumsOfOnes[i]
+	model.Stack = model.Stack[:0]
+	model.Observed = nil
+	model.contradiction = -1
+	model.trail = model.trail[:0]
+	model.decisions = model.decisions[:0]
+	model.backtracks = 0
+	model.observations, model.propagations, model.bans = 0, 0, 0
 
-		if amount == 0 {
-			return ModelFalse
-		}
+	model.resetHeuristic()
+	model.resetGlobals()
+}
 
-		entropy := model.Entropies[i]
-		if amount <= 1 || !(entropy < min) {
-			continue
-		}
-		noise := 1E-6 * rand.Float64()
-		if !(entropy+noise < min) {
-			continue
+func (model *Model) resetHeuristic() {
+	if model.Heuristic == nil {
+		model.Heuristic = &MinEntropyQueue{}
+	}
+	model.Heuristic.Reset(model)
+}
+
+func (model *Model) Observe() ModelResult {
+	if model.contradiction != -1 {
+		for _, l := range model.Listeners {
+			l.OnContradiction(model.contradiction)
 		}
-		min = entropy + noise
-		argmin = i
+		return ModelFalse
 	}
 
+	argmin := model.Heuristic.Select(model)
+
 	if argmin == -1 {
-		model.Observed = make([]int, model.Fmx*model.Fmy)
-		for i := range model.Wave {
-			for t := 0; t < model.T; t++ {
-				if model.Wave[i][t] {
-					model.Observed[i] = t
-					break
-				}
+		model.Observed = make([]int, len(model.SumsOfOnes))
+		for i := range model.Observed {
+			if model.Masked(i) {
+				model.Observed[i] = -1
+			} else if t := model.cellWave(i).First(); t >= 0 {
+				model.Observed[i] = t
 			}
 		}
 		return ModelTrue
 	}
 
 	distribution := make([]float64, model.T)
+	sum := 0.0
 	for t := range distribution {
-		if model.Wave[argmin][t] {
-			distribution[t] = model.Weights[t]
+		if model.Possible(argmin, t) {
+			distribution[t], _ = model.weight(argmin, t)
+			sum += distribution[t]
 		} else {
 			distribution[t] = 0
 		}
 	}
 
-	r := RandomDistribution(distribution, rand.Float64())
+	//every pattern left has weight zero, pick one of them uniformly
+	if sum == 0 {
+		for t := range distribution {
+			if model.Possible(argmin, t) {
+				distribution[t] = 1
+			}
+		}
+	}
+
+	r := RandomDistribution(distribution, model.random.Float64())
+	model.observations++
+
+	for _, l := range model.Listeners {
+		l.OnObserve(argmin, r, model.Entropies[argmin])
+	}
+
+	if model.Backtracking {
+		model.decide(argmin, r)
+	}
 
-	w := model.Wave[argmin]
 	for t := 0; t < model.T; t++ {
-		if w[t] != (t == r) {
+		if model.Possible(argmin, t) != (t == r) {
 			model.Ban(argmin, t)
 		}
 	}
@@ -178,62 +411,130 @@ func (model *Model) Observe() ModelResult {
 }
 
 func (model *Model) Propagate() {
-	for model.StackSize > 0 {
-		e1 := model.Stack[model.StackSize-1]
-		model.StackSize--
-
-		i1 := e1.A
-		x1 := i1 % model.Fmx
-		y1 := i1 / model.Fmx
+	model.propagate(context.Background())
+}
 
-		for d := 0; d < 4; d++ {
-			dx, dy := Dx[d], Dy[d]
-			x2, y2 := x1+dx, y1+dy
-			if model.OnBoundary(x2, y2) {
+// BanUnsupported bans the patterns that no pattern supports in the direction of an existing neighbour and
+// propagates, their support counts start at zero so propagation alone never bans them
+func (model *Model) BanUnsupported() {
+	for i := 0; i < len(model.SumsOfOnes); i++ {
+		for d := 0; d < model.directions; d++ {
+			if _, ok := model.topology.Neighbor(i, model.topology.Opposite(d)); !ok {
 				continue
 			}
 
-			if x2 < 0 {
-				x2 += model.Fmx
-			} else if x2 >= model.Fmx {
-				x2 -= model.Fmx
+			for t := 0; t < model.T; t++ {
+				if model.Possible(i, t) && model.Compatible[(i*model.T+t)*model.directions+d] == 0 {
+					model.Ban(i, t)
+				}
 			}
+		}
+	}
+
+	model.Propagate()
+}
+
+func (model *Model) propagate(ctx context.Context) error {
+	done := ctx.Done()
+	model.propagations++
+
+	for _, l := range model.Listeners {
+		l.OnPropagateStart()
+	}
+	defer func() {
+		for _, l := range model.Listeners {
+			l.OnPropagateEnd()
+		}
+	}()
+
+	//stop at the first contradiction so the state around it can still be inspected
+	for n := 0; model.contradiction == -1; n++ {
+		if len(model.Stack) == 0 && !model.enforceGlobals() {
+			break
+		}
 
-			if y2 < 0 {
-				y2 += model.Fmy
-			} else if y2 >= model.Fmy {
-				y2 -= model.Fmy
+		//checking the context on every ban is too expensive
+		if done != nil && n%4096 == 0 {
+			select {
+			case <-done:
+				return contextError(ctx)
+			default:
 			}
+		}
+
+		e1 := model.Stack[len(model.Stack)-1]
+		model.Stack = model.Stack[:len(model.Stack)-1]
+
+		i1 := e1.A
 
-			i2 := x2 + y2*model.Fmx
+		for d := 0; d < model.directions; d++ {
+			i2, ok := model.topology.Neighbor(i1, d)
+			if !ok || model.Excluded(i2) {
+				continue
+			}
 
 			for _, t2 := range model.Propagator[d][e1.B] {
-				model.Compatible[i2][t2][d]--
-				if model.Compatible[i2][t2][d] == 0 {
+				k := (i2*model.T+t2)*model.directions + d
+				model.Compatible[k]--
+				if model.Compatible[k] == 0 {
 					model.Ban(i2, t2)
 				}
 			}
 		}
 	}
+
+	return nil
 }
 
 func (model *Model) Ban(i, t int) {
-	model.Wave[i][t] = false
+	if !model.Possible(i, t) {
+		return
+	}
+	model.Wave.Unset(i*model.words*64 + t)
+
+	compatible := model.Compatible[(i*model.T+t)*model.directions:]
+	for d := 0; d < model.directions; d++ {
+		compatible[d] = 0
+	}
+
+	model.Stack = append(model.Stack, IntTuple{A: i, B: t})
+	model.bans++
+
+	if model.Backtracking && len(model.decisions) > 0 {
+		model.trail = append(model.trail, IntTuple{A: i, B: t})
+	}
 
-	for d := 0; d < 4; d++ {
-		model.Compatible[i][t][d] = 0
+	for _, l := range model.Listeners {
+		l.OnBan(i, t)
 	}
 
-	model.Stack[model.StackSize] = IntTuple{A: i, B: t}
-	model.StackSize++
+	if model.WeightMaps == nil {
+		sum := model.SumsOfWeights[i]
+		model.Entropies[i] += model.SumsOfWeightLogWeights[i]/sum - math.Log10(sum)
+
+		model.SumsOfOnes[i]--
+		model.SumsOfWeights[i] -= model.Weights[t]
+		model.SumsOfWeightLogWeights[i] -= model.WeightLogWeights[t]
+
+		sum = model.SumsOfWeights[i]
+		model.Entropies[i] -= model.SumsOfWeightLogWeights[i]/sum - math.Log10(sum)
+	} else {
+		w, wlw := model.weight(i, t)
+		model.SumsOfOnes[i]--
+		model.SumsOfWeights[i] -= w
+		model.SumsOfWeightLogWeights[i] -= wlw
+		model.Entropies[i] = entropy(model.SumsOfWeights[i], model.SumsOfWeightLogWeights[i])
+	}
 
-	sum := model.SumsOfWeights[i]
-	model.Entropies[i] += model.SumsOfWeightLogWeights[i]/sum - math.Log10(sum)
+	if model.SumsOfOnes[i] == 0 && model.contradiction == -1 && !model.Excluded(i) {
+		model.contradiction = i
+	}
 
-	model.SumsOfOnes[i]--
-	model.SumsOfWeights[i] -= model.Weights[t]
-	model.SumsOfWeightLogWeights[i] -= model.WeightLogWeights[t]
+	if model.Heuristic != nil {
+		model.Heuristic.Update(model, i)
+	}
 
-	sum = model.SumsOfWeights[i]
-	model.Entropies[i] -= model.SumsOfWeightLogWeights[i]/sum - math.Log10(sum)
+	for _, g := range model.Globals {
+		g.Ban(model, i, t)
+	}
 }
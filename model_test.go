@@ -0,0 +1,70 @@
+package WaveFunctionCollapse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSameSeedSameOutput(t *testing.T) {
+	models := map[string]func() *Model{
+		"overlapping": func() *Model {
+			return NewOverlappingModel(testSample(), 3, 24, 24, true, true, 8, 0, 7).Model
+		},
+		"tiled": func() *Model {
+			model := NewTiledModel(testTiles(), 24, 24, true, false, 7)
+			model.SetBacktracking(1000, 0)
+			return model.Model
+		},
+	}
+
+	for name, build := range models {
+		first, second := build(), build()
+		if !first.Run(0) || !second.Run(0) {
+			t.Errorf("%s: run failed", name)
+			continue
+		}
+		if !reflect.DeepEqual(first.Observed, second.Observed) {
+			t.Errorf("%s: two models with the same seed differ", name)
+		}
+
+		observed := first.Observed
+		if !first.Run(0) || !reflect.DeepEqual(observed, first.Observed) {
+			t.Errorf("%s: a rerun with the same seed differs", name)
+		}
+	}
+}
+
+func TestPeriodicAfterConstruction(t *testing.T) {
+	model := NewOverlappingModel(testSample(), 3, 16, 16, true, false, 8, 0, 1)
+	model.Periodic = true
+	if !model.Run(0) {
+		t.Fatal("run failed")
+	}
+
+	for i := range model.Observed {
+		if model.Excluded(i) {
+			t.Fatalf("cell %d is excluded in a periodic output", i)
+		}
+	}
+	if !validAdjacency(model.Model) {
+		t.Fatal("neighbouring cells do not agree across the wrapped edges")
+	}
+}
+
+func BenchmarkOverlappingModel(b *testing.B) {
+	sample := testSample()
+	b.ReportAllocs()
+	for k := 0; k < b.N; k++ {
+		model := NewOverlappingModel(sample, 3, 64, 64, true, true, 8, 0, int64(k))
+		model.Run(0)
+	}
+}
+
+func BenchmarkTiledModel(b *testing.B) {
+	info := testTiles()
+	b.ReportAllocs()
+	for k := 0; k < b.N; k++ {
+		model := NewTiledModel(info, 64, 64, true, false, int64(k))
+		model.Run(0)
+	}
+}
@@ -13,6 +13,11 @@ type OverlappingModel struct {
 	Patterns [][]uint8
 	Colors   []color.Color
 	Ground   int
+	Anchors  []Anchor
+	Known    []int
+
+	Sample        [][]uint8
+	PeriodicInput bool
 }
 
 func (model *OverlappingModel) ColorModel() color.Model {
@@ -30,7 +35,11 @@ func (model *OverlappingModel) Bounds() image.Rectangle {
 }
 
 func (model *OverlappingModel) At(x, y int) color.Color {
-	if model.Model.Observed != nil {
+	if model.Masked(x + y*model.Fmx) {
+		return model.ColorModel().Convert(color.Transparent)
+	} else if model.Known != nil && model.Known[x+y*model.Fmx] >= 0 {
+		return model.ColorModel().Convert(model.Colors[model.Known[x+y*model.Fmx]])
+	} else if model.Model.Observed != nil {
 		return model.ObservedColor(x, y)
 	} else {
 		return model.UnobservedColor(x, y)
@@ -50,7 +59,13 @@ func (model *OverlappingModel) ObservedColor(x, y int) color.Color {
 		dx = model.N - 1
 	}
 
-	c := model.Colors[model.Patterns[model.Observed[x-dx+(y-dy)*model.Fmx]][dx+dy*model.N]]
+	//the last rows and columns are covered by a cell that may be masked
+	t := model.Observed[x-dx+(y-dy)*model.Fmx]
+	if t < 0 {
+		return model.ColorModel().Convert(color.Transparent)
+	}
+
+	c := model.Colors[model.Patterns[t][dx+dy*model.N]]
 	return model.ColorModel().Convert(c)
 }
 
@@ -66,12 +81,12 @@ func (model *OverlappingModel) UnobservedColor(x, y int) color.Color {
 				sy += model.Fmy
 			}
 			s := sx + sy*model.Fmx
-			if model.OnBoundary(sx, sy) {
+			if model.OnBoundary(sx, sy) || model.Masked(s) {
 				continue
 			}
 
 			for t := 0; t < model.T; t++ {
-				if model.Wave[s][t] {
+				if model.Possible(s, t) {
 					contributors++
 					cr, cg, cb, ca := model.Colors[model.Patterns[t][dx+dy*model.N]].RGBA()
 
@@ -99,44 +114,54 @@ func (model *OverlappingModel) UnobservedColor(x, y int) color.Color {
 func (model *OverlappingModel) Clear() {
 	model.Model.ClearModel()
 
-	if model.Ground == 0 {
+	if model.Ground == 0 && len(model.Anchors) == 0 && model.Known == nil {
 		return
 	}
 
-	for x := 0; x < model.Fmx; x++ {
-		for t := 0; t < model.T; t++ {
-			if t != model.Ground {
-				model.Ban(x+(model.Fmy-1)*model.Fmx, t)
+	if model.Ground != 0 {
+		for x := 0; x < model.Fmx; x++ {
+			for t := 0; t < model.T; t++ {
+				if t != model.Ground {
+					model.Ban(x+(model.Fmy-1)*model.Fmx, t)
+				}
+			}
+			for y := 0; y < model.Fmy-1; y++ {
+				model.Ban(x+y*model.Fmx, model.Ground)
 			}
-		}
-		for y := 0; y < model.Fmy-1; y++ {
-			model.Ban(x+y*model.Fmx, model.Ground)
 		}
 	}
 
+	model.applyAnchors()
+	if model.Known != nil {
+		model.applyKnown()
+	}
 	model.Propagate()
 }
 
-func NewOverlappingModel(source image.Image, n, width, height int, periodicInput, periodicOutput bool, symmetry, ground int) (model *OverlappingModel) {
+func NewOverlappingModel(source image.Image, n, width, height int, periodicInput, periodicOutput bool, symmetry, ground int, seed int64) (model *OverlappingModel) {
 	//initialize model specific data
 	model = &OverlappingModel{
 		Model: &Model{
-			Fmx:      width,
-			Fmy:      height,
-			Periodic: periodicOutput,
+			Rules:      &Rules{},
+			Fmx:        width,
+			Fmy:        height,
+			Periodic:   periodicOutput,
+			RandomSeed: seed,
 		},
-		N:      n,
-		Colors: make([]color.Color, 0),
+		N:             n,
+		Colors:        make([]color.Color, 0),
+		PeriodicInput: periodicInput,
 	}
 
-	//register abstract OnBoundary function
-	model.Model.OnBoundary = model.OnBoundary
-
 	//register virtual clear function
 	model.Model.ImplClear = model.Clear
 
+	//register abstract OnBoundary function
+	model.Model.OnBoundary = model.OnBoundary
+
 	smx, smy := source.Bounds().Dx(), source.Bounds().Dy()
 	sample := newUintMatrix(smx, smy)
+	model.Sample = sample
 
 	weights := make(map[int64]int)
 	ordering := make([]int64, 0)
@@ -209,6 +234,7 @@ func NewOverlappingModel(source image.Image, n, width, height int, periodicInput
 		model.Weights[i] = float64(weights[k])
 	}
 
+	model.Propagator = make([][][]int, 4)
 	for d := range model.Propagator {
 		model.Propagator[d] = make([][]int, model.T)
 		for t := 0; t < model.T; t++ {
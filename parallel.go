@@ -0,0 +1,166 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// ParallelResult is the outcome of SolveParallel. Model is the clone that ended the search and Result its run,
+// when every attempt contradicted Result is the run of the last attempt and Model is nil. When the context is done
+// before any attempt ran Result only holds the status and the error of the context.
+type ParallelResult struct {
+	Result
+	Model                    Solver
+	Attempts, Contradictions int
+}
+
+// SolveParallel solves clones of the model with the seeds Seed(), Seed()+1, ... on GOMAXPROCS goroutines. The first
+// attempt that does not end in a contradiction cancels the others, the model itself is left untouched.
+func SolveParallel(parent context.Context, model Solver, attempts, limit int) ParallelResult {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	if attempts < 1 {
+		attempts = 1
+	}
+	workers := runtime.GOMAXPROCS(0)
+	if workers > attempts {
+		workers = attempts
+	}
+
+	base := model.Seed()
+	seeds := make(chan int64)
+	go func() {
+		defer close(seeds)
+		for k := 0; k < attempts; k++ {
+			select {
+			case seeds <- base + int64(k):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var result ParallelResult
+	finished := false
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			//every worker reuses one clone for its attempts
+			clone := model.Clone()
+			for seed := range seeds {
+				clone.SetSeed(seed)
+				r := clone.Solve(ctx, limit)
+
+				mutex.Lock()
+				won := false
+				if !finished {
+					result.Attempts++
+					result.Result = r
+					if r.Status == StatusContradiction {
+						result.Contradictions++
+					} else {
+						finished = true
+						if r.Status == StatusSuccess || r.Status == StatusLimitReached {
+							result.Model, won = clone, true
+						}
+						cancel()
+					}
+				}
+				mutex.Unlock()
+
+				if won {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if result.Attempts == 0 {
+		result.Result = Result{Err: contextError(parent), Seed: base, Status: StatusCancelled}
+		if result.Err == ErrTimeout {
+			result.Status = StatusTimeout
+		}
+	}
+
+	return result
+}
+
+// clone copies the configuration and the constraints of the model to a model with a fresh wave on the same rules.
+// The listeners are not copied, the clone creates its random source with the source factory of the model. A clone
+// of a model with a source of another kind than rand.NewSource and no factory fails to run.
+func (model *Model) clone() *Model {
+	clone := &Model{
+		Rules:           model.Rules,
+		Fmx:             model.Fmx,
+		Fmy:             model.Fmy,
+		Periodic:        model.Periodic,
+		RandomSeed:      model.RandomSeed,
+		Backtracking:    model.Backtracking,
+		BacktrackBudget: model.BacktrackBudget,
+		BacktrackDepth:  model.BacktrackDepth,
+		Constraints:     append([]CellConstraint(nil), model.Constraints...),
+		Mask:            model.Mask,
+		WeightMaps:      model.WeightMaps,
+		OnBoundary:      model.OnBoundary,
+		NewSource:       model.NewSource,
+	}
+
+	if model.Source != nil && model.NewSource == nil &&
+		reflect.TypeOf(model.Source) != reflect.TypeOf(rand.NewSource(0)) {
+		clone.cloneErr = WFCError("the random source can not be cloned, set a source factory")
+	}
+
+	if model.Heuristic != nil {
+		clone.Heuristic = model.Heuristic.Clone()
+	}
+	for _, g := range model.Globals {
+		clone.Globals = append(clone.Globals, g.Clone())
+	}
+
+	return clone
+}
+
+func (model *OverlappingModel) Clone() Solver {
+	clone := *model
+	clone.Model = model.Model.clone()
+	clone.ImplClear = clone.Clear
+	clone.Model.OnBoundary = clone.OnBoundary
+	return &clone
+}
+
+func (model *TiledModel) Clone() Solver {
+	clone := *model
+	clone.Model = model.Model.clone()
+	clone.ImplClear = clone.Clear
+	clone.Model.OnBoundary = clone.OnBoundary
+	return &clone
+}
+
+func (model *VoxelModel) Clone() Solver {
+	clone := *model
+	clone.Model = model.Model.clone()
+	return &clone
+}
+
+func (model *HexModel) Clone() Solver {
+	clone := *model
+	clone.Model = model.Model.clone()
+	return &clone
+}
+
+func (model *GraphModel) Clone() Solver {
+	clone := *model
+	clone.Model = model.Model.clone()
+	clone.ImplClear = clone.Clear
+	return &clone
+}
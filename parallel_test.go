@@ -0,0 +1,117 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSolveParallelSharedRules(t *testing.T) {
+	model := NewOverlappingModel(testSample(), 3, 24, 24, true, true, 8, 0, 1)
+	result := SolveParallel(context.Background(), model, 8, 0)
+	if result.Model == nil {
+		t.Fatalf("every attempt failed: %v", result.Err)
+	}
+	if result.Model.(*OverlappingModel).Rules != model.Rules {
+		t.Error("the clone does not share the rules of the model")
+	}
+}
+
+func TestClonesShareRules(t *testing.T) {
+	model := NewTiledModel(testTiles(), 16, 16, false, false, 1)
+	model.Backtracking = true
+
+	clones := make([]*TiledModel, 8)
+	for k := range clones {
+		clones[k] = model.Clone().(*TiledModel)
+		clones[k].SetSeed(int64(k))
+	}
+
+	var wg sync.WaitGroup
+	for _, clone := range clones {
+		wg.Add(1)
+		go func(clone *TiledModel) {
+			defer wg.Done()
+			clone.Run(0)
+		}(clone)
+	}
+	wg.Wait()
+
+	for k, clone := range clones {
+		if clone.Observed != nil && !validAdjacency(clone.Model) {
+			t.Errorf("clone %d broke the adjacency rules", k)
+		}
+	}
+}
+
+func TestInitWithoutRules(t *testing.T) {
+	model := &Model{Fmx: 4, Fmy: 4}
+	if err := model.RunContext(context.Background(), 0); err == nil {
+		t.Fatal("model without rules ran")
+	}
+}
+
+func TestSolveParallelDoneContext(t *testing.T) {
+	model := NewTiledModel(testTiles(), 8, 8, false, false, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := SolveParallel(ctx, model, 4, 0)
+	if result.Model != nil || result.Status != StatusCancelled || result.Err != ErrCancelled {
+		t.Errorf("done context gave %v, %v", result.Status, result.Err)
+	}
+}
+
+func TestSolveParallelGraph(t *testing.T) {
+	info := GraphModelInfo{
+		Tiles: []GraphTile{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}},
+		Rules: []GraphRule{{"a", "link", "b"}, {"b", "link", "a"}},
+	}
+	edges := []GraphEdge{{From: 0, To: 1, Label: "link"}, {From: 1, To: 2, Label: "link"}, {From: 2, To: 3, Label: "link"}}
+	model, err := NewGraphModel(info, 4, edges, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := SolveParallel(context.Background(), model, 4, 0)
+	if result.Model == nil {
+		t.Fatalf("every attempt failed: %v", result.Err)
+	}
+	observed := result.Model.(*GraphModel).Observed
+	for _, edge := range edges {
+		if observed[edge.From] == observed[edge.To] {
+			t.Errorf("edge %d-%d joins equal tiles", edge.From, edge.To)
+		}
+	}
+}
+
+type otherSource struct {
+	rand.Source
+}
+
+func TestSolveParallelSource(t *testing.T) {
+	model := NewOverlappingModel(testSample(), 3, 16, 16, true, true, 8, 0, 1)
+	model.SetSourceFactory(func(seed int64) rand.Source {
+		return otherSource{rand.NewSource(seed)}
+	})
+
+	result := SolveParallel(context.Background(), model, 8, 0)
+	if result.Model == nil {
+		t.Fatalf("every attempt failed: %v", result.Err)
+	}
+
+	model.SetSeed(result.Seed)
+	if !model.Run(0) {
+		t.Fatal("the seed of the result contradicts")
+	}
+	if !reflect.DeepEqual(model.Observed, result.Model.(*OverlappingModel).Observed) {
+		t.Error("the seed of the result does not reproduce it")
+	}
+
+	model.SetSource(otherSource{rand.NewSource(1)})
+	if result := SolveParallel(context.Background(), model, 2, 0); result.Model != nil || result.Status != StatusFailed {
+		t.Errorf("source without factory gave %v", result.Status)
+	}
+}
@@ -0,0 +1,261 @@
+package WaveFunctionCollapse
+
+import "image"
+
+// PathConstraint keeps the walkable cells connected. Exits lists for every pattern the directions in which it
+// connects to its neighbour, a nil entry marks a pattern that is not walkable. Two cells are connected when the
+// patterns on both sides of their shared side connect. Without endpoints every walkable cell has to be reachable
+// from every other one, otherwise only the endpoint cells, which are forced to be walkable, have to be connected.
+// The connectivity is only recomputed after a ban that can change which cells are walkable.
+type PathConstraint struct {
+	Exits     [][]bool
+	Endpoints []int
+
+	walkables          []int32
+	dirty              bool
+	required, critical []bool
+	open               []bool
+	disc, low, parent  []int32
+	parentDir, next    []int32
+	sub                []int32
+	stack              []int32
+}
+
+func NewPathConstraint(exits [][]bool, endpoints []int) *PathConstraint {
+	return &PathConstraint{Exits: exits, Endpoints: endpoints}
+}
+
+func (c *PathConstraint) Clone() GlobalConstraint {
+	return NewPathConstraint(c.Exits, c.Endpoints)
+}
+
+func (c *PathConstraint) Reset(model *Model) {
+	cells := len(model.SumsOfOnes)
+	c.walkables = make([]int32, cells)
+	for i := range c.walkables {
+		for t := 0; t < model.T; t++ {
+			if model.Possible(i, t) && c.Exits[t] != nil {
+				c.walkables[i]++
+			}
+		}
+	}
+	c.dirty = true
+
+	c.required = make([]bool, cells)
+	c.critical = make([]bool, cells)
+	c.open = make([]bool, cells*model.directions)
+	c.disc = make([]int32, cells)
+	c.low = make([]int32, cells)
+	c.parent = make([]int32, cells)
+	c.parentDir = make([]int32, cells)
+	c.next = make([]int32, cells)
+	c.sub = make([]int32, cells)
+	c.stack = make([]int32, 0, cells)
+}
+
+// Ban marks the paths for recomputing when a cell loses a walkable pattern, or when every pattern left in a cell
+// is walkable and the cell has to be reached
+func (c *PathConstraint) Ban(model *Model, i, t int) {
+	if c.Exits[t] != nil {
+		c.walkables[i]--
+		c.dirty = true
+	} else if _, must := c.walkable(model, i); must && len(c.Endpoints) == 0 {
+		c.dirty = true
+	}
+}
+
+// walkable reports whether cell i may hold a walkable pattern and whether every pattern left in it is walkable
+func (c *PathConstraint) walkable(model *Model, i int) (can, must bool) {
+	n := int(c.walkables[i])
+	return n > 0, n > 0 && n == model.SumsOfOnes[i]
+}
+
+// force bans the patterns of cell i that are walkable, or the ones that are not
+func (c *PathConstraint) force(model *Model, i int, walkable bool) {
+	for t := 0; t < model.T; t++ {
+		if model.Possible(i, t) && (c.Exits[t] != nil) != walkable {
+			model.Ban(i, t)
+		}
+	}
+}
+
+func (c *PathConstraint) Check(model *Model) int {
+	if !c.dirty {
+		return -1
+	}
+	//the bans made below mark the paths again, they have to be checked once they are propagated
+	c.dirty = false
+
+	directions := model.directions
+
+	for _, i := range c.Endpoints {
+		if can, _ := c.walkable(model, i); !can {
+			return i
+		}
+		c.force(model, i, true)
+	}
+
+	//collect the sides that can still connect and the cells that have to be reached
+	root, total := -1, 0
+	for i := range c.required {
+		c.required[i], c.critical[i] = false, false
+		c.disc[i] = -1
+
+		open := c.open[i*directions : (i+1)*directions]
+		for d := range open {
+			open[d] = false
+		}
+		if model.Excluded(i) {
+			continue
+		}
+
+		for t := 0; t < model.T; t++ {
+			if model.Possible(i, t) && c.Exits[t] != nil {
+				for d, exit := range c.Exits[t] {
+					open[d] = open[d] || exit
+				}
+			}
+		}
+
+		if len(c.Endpoints) == 0 {
+			_, c.required[i] = c.walkable(model, i)
+		}
+	}
+	for _, i := range c.Endpoints {
+		c.required[i] = true
+	}
+	for i, required := range c.required {
+		if required {
+			if root < 0 {
+				root = i
+			}
+			total++
+		}
+	}
+
+	if root < 0 {
+		return -1
+	}
+
+	//depth first search from the root, a cell that separates required cells from the root is an articulation point
+	var timer int32
+	c.disc[root], c.low[root], c.parent[root], c.parentDir[root], c.next[root] = 0, 0, -1, -1, 0
+	c.sub[root] = 1
+	c.stack = append(c.stack[:0], int32(root))
+
+	for len(c.stack) > 0 {
+		v := int(c.stack[len(c.stack)-1])
+
+		if d := int(c.next[v]); d < directions {
+			c.next[v]++
+
+			j, ok := c.neighbor(model, v, d)
+			if !ok || int32(d) == c.parentDir[v] {
+				continue
+			}
+
+			if c.disc[j] < 0 {
+				timer++
+				c.disc[j], c.low[j], c.parent[j], c.next[j] = timer, timer, int32(v), 0
+				c.parentDir[j] = int32(model.topology.Opposite(d))
+				c.sub[j] = 0
+				if c.required[j] {
+					c.sub[j] = 1
+				}
+				c.stack = append(c.stack, int32(j))
+			} else if c.disc[j] < c.low[v] {
+				c.low[v] = c.disc[j]
+			}
+			continue
+		}
+
+		c.stack = c.stack[:len(c.stack)-1]
+		if p := c.parent[v]; p >= 0 {
+			if c.low[v] < c.low[p] {
+				c.low[p] = c.low[v]
+			}
+			c.sub[p] += c.sub[v]
+			if c.low[v] >= c.disc[p] && c.sub[v] > 0 && int(c.sub[v]) < total {
+				c.critical[p] = true
+			}
+		}
+	}
+
+	for i, required := range c.required {
+		if required && c.disc[i] < 0 {
+			return i
+		}
+	}
+
+	for i := range c.required {
+		if c.critical[i] && !c.required[i] {
+			c.force(model, i, true)
+		} else if len(c.Endpoints) == 0 && c.disc[i] < 0 && !model.Excluded(i) {
+			//a walkable cell here could never join the others
+			c.force(model, i, false)
+		}
+	}
+
+	return -1
+}
+
+// neighbor returns the cell next to cell i in direction d if a path can cross their shared side
+func (c *PathConstraint) neighbor(model *Model, i, d int) (int, bool) {
+	if !c.open[i*model.directions+d] {
+		return 0, false
+	}
+
+	j, ok := model.topology.Neighbor(i, d)
+	if !ok || !c.open[j*model.directions+model.topology.Opposite(d)] {
+		return 0, false
+	}
+	return j, true
+}
+
+// PathTile marks a tile as walkable. Sides lists the sides of the tile that connect in the order of Dx, Dy (left,
+// down, right, up) for its first orientation, or for the orientation it names, nil connects all sides.
+type PathTile struct {
+	Name  string `json:"name"`
+	Sides []int  `json:"sides"`
+}
+
+// AddPath keeps the walkable tiles connected, either all of them or the ones at the endpoints
+func (model *TiledModel) AddPath(tiles []PathTile, endpoints []image.Point) error {
+	exits := make([][]bool, model.T)
+	for _, tile := range tiles {
+		patterns, err := model.TilePatterns(tile.Name)
+		if err != nil {
+			return err
+		}
+
+		for k, t := range patterns {
+			//the orientations of a tile are quarter turns, each turns side d into side d+1
+			rotation := k
+			if len(patterns) == 1 {
+				rotation = 0
+			}
+
+			exits[t] = make([]bool, 4)
+			for d := 0; d < 4; d++ {
+				exits[t][d] = tile.Sides == nil
+			}
+			for _, side := range tile.Sides {
+				if side < 0 || side >= 4 {
+					return WFCError("path side out of range")
+				}
+				exits[t][(side+rotation)%4] = true
+			}
+		}
+	}
+
+	cells := make([]int, len(endpoints))
+	for k, p := range endpoints {
+		if p.X < 0 || p.Y < 0 || p.X >= model.Fmx || p.Y >= model.Fmy {
+			return WFCError("path endpoint out of range")
+		}
+		cells[k] = p.X + p.Y*model.Fmx
+	}
+
+	model.AddGlobal(NewPathConstraint(exits, cells))
+	return nil
+}
@@ -0,0 +1,63 @@
+package WaveFunctionCollapse
+
+import "testing"
+
+func TestPathConnectsWalkableTiles(t *testing.T) {
+	tiles := []PathTile{{Name: "line", Sides: []int{1, 3}}, {Name: "corner", Sides: []int{0, 1}}, {Name: "cross"},
+		{Name: "t", Sides: []int{0, 1, 2}}}
+
+	solved := 0
+	for seed := int64(1); seed <= 3; seed++ {
+		model := NewTiledModel(testTiles(), 12, 12, false, false, seed)
+		model.SetBacktracking(2000, 0)
+		if err := model.AddPath(tiles, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !model.Run(0) {
+			continue
+		}
+		solved++
+
+		//a side of a tile is open when the pixel in the middle of the side is black
+		open := func(i, d int) bool {
+			r, _, _, _ := model.Tiles[model.Observed[i]][1+Dx[d]+(1+Dy[d])*model.TileSize].RGBA()
+			return r == 0
+		}
+
+		walkable, reached := 0, 0
+		visited := make([]bool, len(model.Observed))
+		for i := range model.Observed {
+			for d := 0; d < 4; d++ {
+				if open(i, d) {
+					walkable++
+					if reached == 0 {
+						visited[i] = true
+						reached = 1
+						queue := []int{i}
+						for len(queue) > 0 {
+							v := queue[0]
+							queue = queue[1:]
+							for d2 := 0; d2 < 4; d2++ {
+								j, ok := model.topology.Neighbor(v, d2)
+								if ok && !visited[j] && open(v, d2) && open(j, Opposite[d2]) {
+									visited[j] = true
+									reached++
+									queue = append(queue, j)
+								}
+							}
+						}
+					}
+					break
+				}
+			}
+		}
+
+		if reached != walkable {
+			t.Errorf("seed %d: %d of %d walkable cells are connected", seed, reached, walkable)
+		}
+	}
+
+	if solved == 0 {
+		t.Fatal("no run finished")
+	}
+}
@@ -0,0 +1,62 @@
+package WaveFunctionCollapse
+
+import (
+	"bytes"
+	"context"
+	"image"
+)
+
+// Regenerate rerolls the cells of a finished result inside rect, in cell coordinates, with a fresh seed drawn from
+// the random number generator of the model. The observed cells outside rect are kept as they are. When the region
+// can not be refilled, or the limit is reached, the previous result is restored and the error is returned.
+func (model *Model) Regenerate(ctx context.Context, rect image.Rectangle, limit int) error {
+	if model.Observed == nil {
+		return WFCError("model is not observed")
+	}
+
+	//draw the seed before the snapshot so a retry after a failure uses another seed
+	seed := model.random.Int63()
+
+	var saved bytes.Buffer
+	if err := model.Snapshot(&saved); err != nil {
+		return err
+	}
+
+	if err := model.regenerate(ctx, rect, model.Observed, seed, limit); err != nil {
+		if restoreErr := model.Restore(&saved); restoreErr != nil {
+			return restoreErr
+		}
+		return err
+	}
+	return nil
+}
+
+func (model *Model) regenerate(ctx context.Context, rect image.Rectangle, observed []int, seed int64, limit int) error {
+	model.RandomSeed = seed
+	if err := model.prepare(); err != nil {
+		return err
+	}
+
+	for i, t := range observed {
+		x, y := i%model.Fmx, i/model.Fmx%model.Fmy
+		if t < 0 || model.Excluded(i) || image.Pt(x, y).In(rect) {
+			continue
+		}
+
+		for t2 := 0; t2 < model.T; t2++ {
+			if t2 != t && model.Possible(i, t2) {
+				model.Ban(i, t2)
+			}
+		}
+	}
+	model.Propagate()
+
+	if !model.applyConstraints() {
+		for _, l := range model.Listeners {
+			l.OnContradiction(model.contradiction)
+		}
+		return ErrContradiction
+	}
+
+	return model.Resume(ctx, limit)
+}
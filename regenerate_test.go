@@ -0,0 +1,58 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func testRegenerateModel(t *testing.T) *TiledModel {
+	model := NewTiledModel(testTiles(), 16, 16, true, false, 7)
+	model.SetBacktracking(1000, 0)
+	if !model.Run(0) {
+		t.Fatal("model contradicted")
+	}
+	return model
+}
+
+func TestRegenerateKeepsOutside(t *testing.T) {
+	model := testRegenerateModel(t)
+	before := append([]int(nil), model.Observed...)
+	rect := image.Rect(4, 4, 10, 10)
+
+	if err := model.Regenerate(context.Background(), rect, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range model.Observed {
+		if !image.Pt(i%16, i/16).In(rect) && p != before[i] {
+			t.Errorf("cell %d outside the region changed from %d to %d", i, before[i], p)
+		}
+	}
+	if !validAdjacency(model.Model) {
+		t.Error("the regenerated region does not fit its surroundings")
+	}
+}
+
+func TestRegenerateContradictionRestores(t *testing.T) {
+	model := testRegenerateModel(t)
+	before := append([]int(nil), model.Observed...)
+	seed := model.RandomSeed
+
+	//no pattern is left for a cell inside the region
+	for p := 0; p < model.T; p++ {
+		if err := model.BanCell(6+6*16, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := model.Regenerate(context.Background(), image.Rect(4, 4, 10, 10), 0); err != ErrContradiction {
+		t.Fatalf("regenerating an impossible region gave %v", err)
+	}
+	if !reflect.DeepEqual(model.Observed, before) {
+		t.Error("the previous result was not restored")
+	}
+	if model.RandomSeed != seed {
+		t.Errorf("seed %d was not restored to %d", model.RandomSeed, seed)
+	}
+}
@@ -0,0 +1,149 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Status uint8
+
+const (
+	StatusSuccess Status = iota
+	StatusContradiction
+	StatusCancelled
+	StatusTimeout
+	StatusLimitReached
+	StatusFailed
+)
+
+func (status Status) String() string {
+	switch status {
+	case StatusSuccess:
+		return "success"
+	case StatusContradiction:
+		return "contradiction"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusTimeout:
+		return "timed out"
+	case StatusLimitReached:
+		return "iteration limit reached"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Result describes the outcome of a run, Propagations counts the propagation passes
+type Result struct {
+	Status                                       Status
+	Err                                          error
+	Seed                                         int64
+	Observations, Propagations, Bans, Backtracks int
+	Duration                                     time.Duration
+	Contradiction                                *Contradiction
+}
+
+// Contradiction describes the cell without any remaining pattern and the patterns remaining in its neighbours, Cell
+// is Unsatisfiable for a global constraint that no cell can meet
+type Contradiction struct {
+	Cell, X, Y int
+	Neighbors  []Neighbor
+}
+
+type Neighbor struct {
+	Direction, Cell, X, Y int
+	Patterns              []int
+}
+
+func (c *Contradiction) String() string {
+	if c.Cell == Unsatisfiable {
+		return "unsatisfiable global constraint"
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "contradiction at %d,%d", c.X, c.Y)
+	for _, n := range c.Neighbors {
+		fmt.Fprintf(&builder, "; neighbor %d,%d allows %v", n.X, n.Y, n.Patterns)
+	}
+	return builder.String()
+}
+
+// Solve runs the model like RunContext and reports the outcome together with statistics of the run
+func (model *Model) Solve(ctx context.Context, limit int) Result {
+	start := time.Now()
+	err := model.RunContext(ctx, limit)
+	return model.result(err, time.Since(start))
+}
+
+func (model *Model) result(err error, duration time.Duration) Result {
+	result := Result{
+		Err:          err,
+		Seed:         model.RandomSeed,
+		Observations: model.observations,
+		Propagations: model.propagations,
+		Bans:         model.bans,
+		Backtracks:   model.backtracks,
+		Duration:     duration,
+	}
+
+	switch err {
+	case nil:
+		result.Status = StatusSuccess
+	case ErrContradiction:
+		result.Status = StatusContradiction
+		result.Contradiction = model.describeContradiction()
+	case ErrCancelled:
+		result.Status = StatusCancelled
+	case ErrTimeout:
+		result.Status = StatusTimeout
+	case ErrLimitReached:
+		result.Status = StatusLimitReached
+	default:
+		result.Status = StatusFailed
+	}
+
+	return result
+}
+
+// coordinates of a cell in a grid of Fmx columns, topologies without columns only have an x coordinate
+func (model *Model) coordinates(i int) (int, int) {
+	if model.Fmx <= 0 {
+		return i, 0
+	}
+	return i % model.Fmx, i / model.Fmx
+}
+
+func (model *Model) describeContradiction() *Contradiction {
+	i := model.contradiction
+	if i == -1 {
+		return nil
+	}
+	if i < 0 {
+		return &Contradiction{Cell: i, X: -1, Y: -1}
+	}
+
+	x, y := model.coordinates(i)
+	contradiction := &Contradiction{Cell: i, X: x, Y: y}
+
+	for d := 0; d < model.directions; d++ {
+		i2, ok := model.topology.Neighbor(i, d)
+		if !ok {
+			continue
+		}
+
+		neighbor := Neighbor{Direction: d, Cell: i2, Patterns: make([]int, 0)}
+		neighbor.X, neighbor.Y = model.coordinates(i2)
+		for t := 0; t < model.T; t++ {
+			if model.Possible(i2, t) {
+				neighbor.Patterns = append(neighbor.Patterns, t)
+			}
+		}
+		contradiction.Neighbors = append(contradiction.Neighbors, neighbor)
+	}
+
+	return contradiction
+}
@@ -0,0 +1,52 @@
+package WaveFunctionCollapse
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResultContradiction(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		model := NewTiledModel(testTiles(), 16, 16, false, false, seed)
+		result := model.Solve(context.Background(), 0)
+		if result.Status != StatusContradiction {
+			if result.Contradiction != nil {
+				t.Errorf("seed %d: contradiction described for %v", seed, result.Status)
+			}
+			continue
+		}
+
+		c := result.Contradiction
+		if c.X != c.Cell%model.Fmx || c.Y != c.Cell/model.Fmx || model.SumsOfOnes[c.Cell] != 0 {
+			t.Fatalf("seed %d: contradiction at cell %d (%d,%d) with %d patterns left", seed, c.Cell, c.X, c.Y,
+				model.SumsOfOnes[c.Cell])
+		}
+
+		expected := 0
+		for d := 0; d < 4; d++ {
+			if _, ok := model.topology.Neighbor(c.Cell, d); ok {
+				expected++
+			}
+		}
+		if len(c.Neighbors) != expected {
+			t.Fatalf("seed %d: %d neighbours described, the cell has %d", seed, len(c.Neighbors), expected)
+		}
+
+		for _, n := range c.Neighbors {
+			if n.X != c.X+Dx[n.Direction] || n.Y != c.Y+Dy[n.Direction] || n.Cell != n.X+n.Y*model.Fmx {
+				t.Fatalf("seed %d: neighbour %d,%d in direction %d of %d,%d", seed, n.X, n.Y, n.Direction, c.X, c.Y)
+			}
+			if len(n.Patterns) != model.SumsOfOnes[n.Cell] {
+				t.Fatalf("seed %d: %d patterns listed for a neighbour with %d", seed, len(n.Patterns),
+					model.SumsOfOnes[n.Cell])
+			}
+			for _, p := range n.Patterns {
+				if !model.Possible(n.Cell, p) {
+					t.Fatalf("seed %d: banned pattern %d listed for a neighbour", seed, p)
+				}
+			}
+		}
+		return
+	}
+	t.Fatal("no seed contradicted")
+}
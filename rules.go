@@ -0,0 +1,37 @@
+package WaveFunctionCollapse
+
+import (
+	"math"
+	"sync"
+)
+
+// Rules are the compiled rules of a model: the patterns, their adjacency and weights, and the cells they are placed
+// on. Once compiled the rules are read-only and can be shared by any number of models solving concurrently, every
+// model keeps its own wave. Clone creates such a model from a built one.
+type Rules struct {
+	T                                                    int
+	Propagator                                           [][][]int
+	Weights, WeightLogWeights                            []float64
+	SumOfWeights, SumOfWeightLogWeights, StartingEntropy float64
+
+	//set when the rules are built, without a topology every model places its cells on its own Fmx x Fmy grid
+	Topology Topology `json:"-"`
+
+	once sync.Once
+}
+
+// Compile derives the entropy terms from the weights, it only runs the first time it is called
+func (rules *Rules) Compile() {
+	rules.once.Do(func() {
+		rules.WeightLogWeights = make([]float64, rules.T)
+		rules.SumOfWeights, rules.SumOfWeightLogWeights = 0, 0
+
+		for t := range rules.WeightLogWeights {
+			rules.WeightLogWeights[t] = rules.Weights[t] * math.Log10(rules.Weights[t])
+			rules.SumOfWeights += rules.Weights[t]
+			rules.SumOfWeightLogWeights += rules.WeightLogWeights[t]
+		}
+
+		rules.StartingEntropy = math.Log10(rules.SumOfWeights) - rules.SumOfWeightLogWeights/rules.SumOfWeights
+	})
+}
@@ -0,0 +1,234 @@
+package WaveFunctionCollapse
+
+import (
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	snapshotMagic   = "WFCS"
+	snapshotVersion = 2
+)
+
+type snapshotVersionHeader struct {
+	Magic   [4]byte
+	Version uint16
+}
+
+type snapshotHeader struct {
+	Cells, Patterns, Directions uint32
+	Seed                        int64
+	Draws                       uint64
+	Observations                uint64
+	Contradiction               int32
+	Stack                       uint32
+	Observed                    uint8
+}
+
+// Snapshot writes the state of the wave, the propagation stack and the random number generator to w in a
+// versioned, compressed binary format. The backtracking history is not part of the snapshot.
+func (model *Model) Snapshot(w io.Writer) error {
+	if model.Wave == nil {
+		return WFCError("model is not initialized")
+	}
+
+	version := snapshotVersionHeader{Version: snapshotVersion}
+	copy(version.Magic[:], snapshotMagic)
+	if err := binary.Write(w, binary.LittleEndian, version); err != nil {
+		return err
+	}
+
+	compressor, err := flate.NewWriter(w, flate.BestSpeed)
+	if err != nil {
+		return err
+	}
+	buffer := bufio.NewWriter(compressor)
+
+	header := snapshotHeader{
+		Cells:         uint32(len(model.SumsOfOnes)),
+		Patterns:      uint32(model.T),
+		Directions:    uint32(len(model.Compatible) / (len(model.SumsOfOnes) * model.T)),
+		Seed:          model.RandomSeed,
+		Draws:         model.counter.draws,
+		Observations:  uint64(model.observations),
+		Contradiction: int32(model.contradiction),
+		Stack:         uint32(len(model.Stack)),
+	}
+	if model.Observed != nil {
+		header.Observed = 1
+	}
+
+	sums := make([]int32, len(model.SumsOfOnes))
+	for i, v := range model.SumsOfOnes {
+		sums[i] = int32(v)
+	}
+
+	stack := make([]int32, 2*len(model.Stack))
+	for k, e := range model.Stack {
+		stack[2*k], stack[2*k+1] = int32(e.A), int32(e.B)
+	}
+
+	data := []interface{}{
+		header,
+		model.Wave,
+		model.Compatible,
+		sums,
+		model.SumsOfWeights,
+		model.SumsOfWeightLogWeights,
+		model.Entropies,
+		model.Noise,
+		stack,
+	}
+
+	if model.Observed != nil {
+		observed := make([]int32, len(model.Observed))
+		for i, t := range model.Observed {
+			observed[i] = int32(t)
+		}
+		data = append(data, observed)
+	}
+
+	for _, d := range data {
+		if err := binary.Write(buffer, binary.LittleEndian, d); err != nil {
+			return err
+		}
+	}
+
+	if err := buffer.Flush(); err != nil {
+		return err
+	}
+	return compressor.Close()
+}
+
+// Restore reads a snapshot written by Snapshot of a model with the same rules and dimensions, observation can be
+// continued with Resume.
+func (model *Model) Restore(r io.Reader) error {
+	if model.Wave == nil {
+		if err := model.Init(); err != nil {
+			return err
+		}
+	}
+
+	var version snapshotVersionHeader
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return err
+	}
+
+	if string(version.Magic[:]) != snapshotMagic {
+		return WFCError("not a snapshot")
+	}
+	if version.Version != snapshotVersion {
+		return WFCError("unsupported snapshot version")
+	}
+
+	decompressor := flate.NewReader(r)
+	defer decompressor.Close()
+	buffer := bufio.NewReader(decompressor)
+
+	var header snapshotHeader
+	if err := binary.Read(buffer, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if int(header.Cells) != len(model.SumsOfOnes) || int(header.Patterns) != model.T ||
+		int(header.Directions)*len(model.SumsOfOnes)*model.T != len(model.Compatible) {
+		return WFCError("snapshot does not match the model")
+	}
+
+	//a run draws the noise of every cell and a number for every observation, the rest allows for regenerations
+	draws := uint64(header.Cells)*uint64(header.Patterns) + header.Observations
+	if header.Draws > draws || int64(header.Stack) > int64(header.Cells)*int64(header.Patterns) ||
+		header.Contradiction < Unsatisfiable || int64(header.Contradiction) >= int64(header.Cells) {
+		return WFCError("corrupt snapshot")
+	}
+
+	//decode into new buffers so a broken stream leaves the model as it was
+	wave := make(Bitset, len(model.Wave))
+	compatible := make([]int32, len(model.Compatible))
+	sums := make([]int32, header.Cells)
+	sumsOfWeights := make([]float64, header.Cells)
+	sumsOfWeightLogWeights := make([]float64, header.Cells)
+	entropies := make([]float64, header.Cells)
+	noise := make([]float64, header.Cells)
+	stack := make([]int32, 2*header.Stack)
+	data := []interface{}{
+		wave,
+		compatible,
+		sums,
+		sumsOfWeights,
+		sumsOfWeightLogWeights,
+		entropies,
+		noise,
+		stack,
+	}
+
+	var observed []int32
+	if header.Observed != 0 {
+		observed = make([]int32, header.Cells)
+		data = append(data, observed)
+	}
+
+	for _, d := range data {
+		if err := binary.Read(buffer, binary.LittleEndian, d); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range sums {
+		if v < 0 || int(v) > model.T {
+			return WFCError("corrupt snapshot")
+		}
+	}
+	for k, v := range stack {
+		if v < 0 || k%2 == 0 && v >= int32(header.Cells) || k%2 == 1 && v >= int32(header.Patterns) {
+			return WFCError("corrupt snapshot")
+		}
+	}
+	for _, t := range observed {
+		if t < -1 || t >= int32(header.Patterns) {
+			return WFCError("corrupt snapshot")
+		}
+	}
+
+	copy(model.Wave, wave)
+	copy(model.Compatible, compatible)
+	for i, v := range sums {
+		model.SumsOfOnes[i] = int(v)
+	}
+	copy(model.SumsOfWeights, sumsOfWeights)
+	copy(model.SumsOfWeightLogWeights, sumsOfWeightLogWeights)
+	copy(model.Entropies, entropies)
+	copy(model.Noise, noise)
+
+	model.Stack = model.Stack[:0]
+	for k := 0; k < len(stack); k += 2 {
+		model.Stack = append(model.Stack, IntTuple{A: int(stack[k]), B: int(stack[k+1])})
+	}
+
+	model.Observed = nil
+	if observed != nil {
+		model.Observed = make([]int, len(observed))
+		for i, t := range observed {
+			model.Observed[i] = int(t)
+		}
+	}
+
+	model.contradiction = int(header.Contradiction)
+	model.trail = model.trail[:0]
+	model.decisions = model.decisions[:0]
+	model.backtracks = 0
+	model.observations, model.propagations, model.bans = int(header.Observations), 0, 0
+
+	//replay the random number generator up to the state of the snapshot
+	model.RandomSeed = header.Seed
+	model.reseed()
+	for k := uint64(0); k < header.Draws; k++ {
+		model.counter.Int63()
+	}
+
+	model.resetHeuristic()
+	model.resetGlobals()
+
+	return nil
+}
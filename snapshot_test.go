@@ -0,0 +1,71 @@
+package WaveFunctionCollapse
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRestoreTruncatedSnapshot(t *testing.T) {
+	info := testTiles()
+	source := NewTiledModel(info, 10, 10, false, false, 1)
+	if !source.Run(0) {
+		t.Fatal("run failed")
+	}
+
+	var buffer bytes.Buffer
+	if err := source.Snapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+
+	model := NewTiledModel(info, 10, 10, false, false, 2)
+	if !model.Run(0) {
+		t.Fatal("run failed")
+	}
+	observed := append([]int(nil), model.Observed...)
+	wave := append(Bitset(nil), model.Wave...)
+
+	for _, n := range []int{len(data) / 3, len(data) / 2, len(data) - 8} {
+		if err := model.Restore(bytes.NewReader(data[:n])); err == nil {
+			t.Fatalf("restoring %d of %d bytes succeeded", n, len(data))
+		}
+
+		for i := range observed {
+			if model.Observed[i] != observed[i] {
+				t.Fatalf("cell %d was overwritten by a truncated snapshot", i)
+			}
+		}
+		for k := range wave {
+			if model.Wave[k] != wave[k] {
+				t.Fatal("the wave was overwritten by a truncated snapshot")
+			}
+		}
+	}
+
+	if err := model.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range source.Observed {
+		if model.Observed[i] != source.Observed[i] {
+			t.Fatalf("cell %d was not restored", i)
+		}
+	}
+}
+
+func TestRestoreRejectsExcessiveDraws(t *testing.T) {
+	model := NewTiledModel(testTiles(), 10, 10, false, false, 1)
+	if !model.Run(0) {
+		t.Fatal("run failed")
+	}
+
+	//a replay of this many draws would never finish
+	model.counter.draws = 1 << 62
+	var buffer bytes.Buffer
+	if err := model.Snapshot(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := model.Restore(&buffer); err != WFCError("corrupt snapshot") {
+		t.Fatalf("restore returned %v", err)
+	}
+}
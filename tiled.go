@@ -19,6 +19,7 @@ type TiledModel struct {
 	TileSize  int
 	Tiles     [][]color.Color
 	TileNames []string
+	Borders   [4][]int
 }
 
 func (model *TiledModel) ColorModel() color.Model {
@@ -36,7 +37,9 @@ func (model *TiledModel) Bounds() image.Rectangle {
 }
 
 func (model *TiledModel) At(x, y int) color.Color {
-	if model.Model.Observed != nil {
+	if model.Masked(x/model.TileSize + y/model.TileSize*model.Fmx) {
+		return model.ColorModel().Convert(color.Transparent)
+	} else if model.Model.Observed != nil {
 		return model.ObservedColor(x, y)
 	} else {
 		return model.UnobservedColor(x, y)
@@ -57,19 +60,13 @@ func (model *TiledModel) UnobservedColor(x, y int) color.Color {
 	tx, ty := x/model.TileSize, y/model.TileSize
 	xt, yt := x%model.TileSize, y%model.TileSize
 
-	a := model.Wave[tx+ty*model.Fmx]
+	i := tx + ty*model.Fmx
 
-	var amount = 0
+	var amount = model.SumsOfOnes[i]
 	var sum float64 = 0
 
-	for _, b := range a {
-		if b {
-			amount++
-		}
-	}
-
 	for t := 0; t < model.T; t++ {
-		if a[t] {
+		if model.Possible(i, t) {
 			sum += model.Weights[t]
 		}
 	}
@@ -81,7 +78,7 @@ func (model *TiledModel) UnobservedColor(x, y int) color.Color {
 	} else {
 		var r, g, b, a float64 = 0, 0, 0, 0
 		for t := 0; t < model.T; t++ {
-			if model.Wave[tx+ty*model.Fmx][t] {
+			if model.Possible(i, t) {
 				cr, cg, cb, ca := model.Tiles[t][xt+yt*model.TileSize].RGBA()
 				r += float64(cr) * model.Weights[t] * lambda
 				g += float64(cg) * model.Weights[t] * lambda
@@ -100,9 +97,10 @@ func (model *TiledModel) UnobservedColor(x, y int) color.Color {
 }
 
 type ModelInfo struct {
-	Tiles []Tile `json:"tiles"`
-	Edges []Edge `json:"edges"`
-	Size  int    `json:"size"`
+	Tiles    []Tile `json:"tiles"`
+	Edges    []Edge `json:"edges"`
+	Vertical []Edge `json:"vertical,omitempty"`
+	Size     int    `json:"size"`
 }
 
 func (info *ModelInfo) Initialize() error {
@@ -157,16 +155,19 @@ func ParseEdge(edge Edge) (leftname string, leftcardinal int, rightname string,
 	return
 }
 
-func NewTiledModel(info ModelInfo, width, height int, periodic, black bool) (model *TiledModel) {
+func NewTiledModel(info ModelInfo, width, height int, periodic, black bool, seed int64) (model *TiledModel) {
 	model = &TiledModel{
 		Model: &Model{
-			Fmx:      width,
-			Fmy:      height,
-			Periodic: periodic,
+			Rules:      &Rules{},
+			Fmx:        width,
+			Fmy:        height,
+			Periodic:   periodic,
+			RandomSeed: seed,
 		},
 		Black:    black,
 		TileSize: info.Size,
 	}
+	model.ImplClear = model.Clear
 
 	//register abstract OnBoundary function
 	model.Model.OnBoundary = model.OnBoundary
@@ -176,28 +177,11 @@ func NewTiledModel(info ModelInfo, width, height int, periodic, black bool) (mod
 
 	model.Weights = make([]float64, 0)
 
-	action := make([][8]int, 0)
-	firstOccurrence := make(map[string]int)
+	action, firstOccurrence := symmetryActions(info.Tiles)
 
 	for _, tile := range info.Tiles {
-		a, b, cardinality := SymmetryFunc(tile.Symmetry)
-		model.T = len(action)
-		firstOccurrence[tile.Name] = model.T
-		symmetryMap := make([][8]int, cardinality)
-		for t := 0; t < cardinality; t++ {
-			symmetryMap[t] = [8]int{
-				model.T + t,
-				model.T + a(t),
-				model.T + a(a(t)),
-				model.T + a(a(a(t))),
-				model.T + b(t),
-				model.T + b(a(t)),
-				model.T + b(a(a(t))),
-				model.T + b(a(a(a(t)))),
-			}
-
-			action = append(action, symmetryMap[t])
-		}
+		_, _, cardinality := SymmetryFunc(tile.Symmetry)
+		model.T = len(model.Tiles)
 
 		if tile.Unique {
 			for t := 0; t < cardinality; t++ {
@@ -222,18 +206,43 @@ func NewTiledModel(info ModelInfo, width, height int, periodic, black bool) (mod
 	}
 
 	model.T = len(action)
+	model.Propagator = sparsePropagator(planarPropagator(info.Edges, action, firstOccurrence))
 
-	var tempPropagator [4][][]bool
-	for d := range model.Propagator {
-		tempPropagator[d] = make([][]bool, model.T)
-		model.Propagator[d] = make([][]int, model.T)
+	return
+}
 
-		for t := 0; t < model.T; t++ {
-			tempPropagator[d][t] = make([]bool, model.T)
+// symmetryActions maps every orientation of every tile to its orientation after each of the 8 symmetry actions
+func symmetryActions(tiles []Tile) (action [][8]int, firstOccurrence map[string]int) {
+	action = make([][8]int, 0)
+	firstOccurrence = make(map[string]int)
+
+	for _, tile := range tiles {
+		a, b, cardinality := SymmetryFunc(tile.Symmetry)
+		T := len(action)
+		firstOccurrence[tile.Name] = T
+		for t := 0; t < cardinality; t++ {
+			action = append(action, [8]int{
+				T + t,
+				T + a(t),
+				T + a(a(t)),
+				T + a(a(a(t))),
+				T + b(t),
+				T + b(a(t)),
+				T + b(a(a(t))),
+				T + b(a(a(a(t)))),
+			})
 		}
 	}
 
-	for _, edge := range info.Edges {
+	return
+}
+
+// planarPropagator derives the adjacency of the 4 planar directions from the left-right edges
+func planarPropagator(edges []Edge, action [][8]int, firstOccurrence map[string]int) [][][]bool {
+	T := len(action)
+	tempPropagator := newPropagator(4, T)
+
+	for _, edge := range edges {
 		leftName, leftCardinal, rightName, rightCardinal := ParseEdge(edge)
 
 		l := action[firstOccurrence[leftName]][leftCardinal]
@@ -252,38 +261,147 @@ func NewTiledModel(info ModelInfo, width, height int, periodic, black bool) (mod
 		tempPropagator[1][action[d][2]][action[u][2]] = true
 	}
 
-	for t2 := 0; t2 < model.T; t2++ {
-		for t1 := 0; t1 < model.T; t1++ {
+	for t2 := 0; t2 < T; t2++ {
+		for t1 := 0; t1 < T; t1++ {
 			tempPropagator[2][t2][t1] = tempPropagator[0][t1][t2]
 			tempPropagator[3][t2][t1] = tempPropagator[1][t1][t2]
 		}
 	}
 
-	var sparsePropagator [4][][]int
-	for d := range sparsePropagator {
-		sparsePropagator[d] = make([][]int, model.T)
-		for t := range sparsePropagator[d] {
-			sparsePropagator[d][t] = make([]int, 0)
+	return tempPropagator
+}
+
+func newPropagator(directions, T int) [][][]bool {
+	propagator := make([][][]bool, directions)
+	for d := range propagator {
+		propagator[d] = make([][]bool, T)
+		for t := range propagator[d] {
+			propagator[d][t] = make([]bool, T)
 		}
 	}
+	return propagator
+}
 
-	for d := 0; d < 4; d++ {
-		for t1 := 0; t1 < model.T; t1++ {
-			for t2 := 0; t2 < model.T; t2++ {
-				if tempPropagator[d][t1][t2] {
-					sparsePropagator[d][t1] = append(sparsePropagator[d][t1], t2)
+func sparsePropagator(dense [][][]bool) [][][]int {
+	propagator := make([][][]int, len(dense))
+	for d := range dense {
+		propagator[d] = make([][]int, len(dense[d]))
+		for t1 := range dense[d] {
+			propagator[d][t1] = make([]int, 0)
+			for t2, ok := range dense[d][t1] {
+				if ok {
+					propagator[d][t1] = append(propagator[d][t1], t2)
 				}
 			}
+		}
+	}
+	return propagator
+}
+
+// TilePatterns returns the patterns of a tile name, either a single orientation ("corner 1") or all orientations
+// of a tile ("corner")
+func (model *TiledModel) TilePatterns(name string) ([]int, error) {
+	patterns := make([]int, 0)
+	for t, tileName := range model.TileNames {
+		if tileName == name || tileName[:strings.LastIndex(tileName, Separator)] == name {
+			patterns = append(patterns, t)
+		}
+	}
+
+	if len(patterns) == 0 {
+		return nil, WFCError("tile not recognized: " + name)
+	}
+	return patterns, nil
+}
+
+// TileBorders lists the tile names allowed along each edge of the output, an empty list allows every tile
+type TileBorders struct {
+	Top    []string `json:"top,omitempty"`
+	Bottom []string `json:"bottom,omitempty"`
+	Left   []string `json:"left,omitempty"`
+	Right  []string `json:"right,omitempty"`
+}
 
-			spCount := len(sparsePropagator[d][t1])
-			model.Propagator[d][t1] = make([]int, spCount)
-			for st := 0; st < spCount; st++ {
-				model.Propagator[d][t1][st] = sparsePropagator[d][t1][st]
+// SetBorders restricts the cells along the edges of the output to the tiles of the borders. Borders holds the
+// allowed patterns of each edge in the order of Dx, Dy, nil allows every pattern.
+func (model *TiledModel) SetBorders(borders TileBorders) error {
+	for d, names := range [4][]string{borders.Left, borders.Bottom, borders.Right, borders.Top} {
+		model.Borders[d] = nil
+		for _, name := range names {
+			patterns, err := model.TilePatterns(name)
+			if err != nil {
+				return err
 			}
+			model.Borders[d] = append(model.Borders[d], patterns...)
 		}
 	}
+	return nil
+}
 
-	return
+// Clear resets the wave and bans the patterns that are not allowed along the edges of the output
+func (model *TiledModel) Clear() {
+	model.Model.ClearModel()
+
+	banned := false
+	for d, allowed := range model.Borders {
+		if allowed == nil {
+			continue
+		}
+
+		for i := range model.SumsOfOnes {
+			x, y := i%model.Fmx+Dx[d], i/model.Fmx+Dy[d]
+			if x >= 0 && y >= 0 && x < model.Fmx && y < model.Fmy || model.Excluded(i) {
+				continue
+			}
+
+			for t := 0; t < model.T; t++ {
+				if !contains(allowed, t) && model.Possible(i, t) {
+					model.Ban(i, t)
+					banned = true
+				}
+			}
+		}
+	}
+
+	if banned {
+		model.Propagate()
+	}
+}
+
+// SelectTile restricts the cell at x, y to the patterns of a tile name
+func (model *TiledModel) SelectTile(x, y int, name string) error {
+	patterns, err := model.TilePatterns(name)
+	if err != nil {
+		return err
+	}
+
+	if len(patterns) == 1 {
+		return model.SelectAt(x, y, patterns[0])
+	}
+
+	for t := 0; t < model.T; t++ {
+		if !contains(patterns, t) {
+			if err := model.BanAt(x, y, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BanTile forbids the patterns of a tile name in the cell at x, y
+func (model *TiledModel) BanTile(x, y int, name string) error {
+	patterns, err := model.TilePatterns(name)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range patterns {
+		if err := model.BanAt(x, y, t); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (model *TiledModel) OnBoundary(x, y int) bool {
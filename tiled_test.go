@@ -0,0 +1,37 @@
+package WaveFunctionCollapse
+
+import "testing"
+
+func TestTileBorders(t *testing.T) {
+	model := NewTiledModel(testTiles(), 12, 12, false, false, 1)
+	model.SetBacktracking(1000, 0)
+	if err := model.SetBorders(TileBorders{Top: []string{"empty"}, Left: []string{"empty", "line"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.SetBorders(TileBorders{Right: []string{"road"}}); err == nil {
+		t.Error("unknown border tile was accepted")
+	}
+	if err := model.SetBorders(TileBorders{Top: []string{"empty"}, Left: []string{"empty", "line"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	empty, _ := model.TilePatterns("empty")
+	line, _ := model.TilePatterns("line")
+	left := append(empty, line...)
+
+	for seed := int64(1); seed <= 5; seed++ {
+		model.SetSeed(seed)
+		if !model.Run(0) {
+			t.Fatalf("seed %d contradicted", seed)
+		}
+
+		for k := 0; k < 12; k++ {
+			if top := model.Observed[k]; !contains(empty, top) {
+				t.Errorf("seed %d: top cell %d holds %s", seed, k, model.TileNames[top])
+			}
+			if side := model.Observed[k*12]; !contains(left, side) {
+				t.Errorf("seed %d: left cell %d holds %s", seed, k, model.TileNames[side])
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -11,8 +12,8 @@ import (
 	"image/png"
 	_ "image/png"
 	"io/ioutil"
-	rand2 "math/rand"
 	"os"
+	"os/signal"
 	"path"
 	"strings"
 	"sync"
@@ -22,28 +23,42 @@ import (
 	"timbeurskens/FileIntercept"
 )
 
-func init() {
-	rand2.Seed(time.Now().UTC().UnixNano())
-}
-
 type Sample struct {
 	Type        string `json:"type"`
 	Name        string `json:"pattern,omitempty"`
 	Width       int    `json:"width"`
 	Height      int    `json:"height"`
+	Depth       int    `json:"depth,omitempty"`
 	N           int    `json:"n,omitempty"`
 	PeriodicIn  bool   `json:"periodic_in,omitempty"`
 	PeriodicOut bool   `json:"periodic_out"`
 	Symmetry    int    `json:"symmetry,omitempty"`
 	Ground      int    `json:"ground,omitempty"`
 	Black       bool   `json:"black,omitempty"`
-	dir         string
+
+	Borders    WaveFunctionCollapse.TileBorders `json:"borders,omitempty"`
+	Anchors    []WaveFunctionCollapse.Anchor    `json:"anchors,omitempty"`
+	WeightMaps map[string]string                `json:"weight_maps,omitempty"`
+	Target     string                           `json:"target,omitempty"`
+	TargetMask string                           `json:"target_mask,omitempty"`
+
+	Backtrack       bool `json:"backtrack,omitempty"`
+	BacktrackBudget int  `json:"backtrack_budget,omitempty"`
+	BacktrackDepth  int  `json:"backtrack_depth,omitempty"`
+
+	Heuristic string `json:"heuristic,omitempty"`
+	Origin    []int  `json:"origin,omitempty"`
+	Mask      string `json:"mask,omitempty"`
+
+	dir string
 }
 
 var (
-	file  = flag.String("in", "samples.json", "json array of samples")
-	reps  = flag.Int("tries", 10, "The number of times to try and find a solution")
-	limit = flag.Int("limit", 0, "Limit the number of iterations, 0 for infinity")
+	file    = flag.String("in", "samples.json", "json array of samples")
+	reps    = flag.Int("tries", 10, "The number of times to try and find a solution")
+	limit   = flag.Int("limit", 0, "Limit the number of iterations, 0 for infinity")
+	seed    = flag.Int64("seed", 0, "The seed of the first try, 0 for a time based seed")
+	timeout = flag.Duration("timeout", 0, "The maximum duration of a single sample, 0 for no timeout")
 )
 
 func main() {
@@ -71,6 +86,9 @@ func main() {
 		return
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	var wg sync.WaitGroup
 	n := len(sampleList)
 	wg.Add(n)
@@ -82,7 +100,7 @@ func main() {
 	for _, sample := range sampleList {
 		sample.dir = dir
 		go func(s Sample) {
-			err, out := ExecuteSample(s)
+			err, out := ExecuteSample(ctx, s)
 			progress.Increment(1)
 
 			//catch error to prevent breaking the goroutine
@@ -103,7 +121,7 @@ func main() {
 	ic.Flush()
 }
 
-func ExecuteSample(sample Sample) (error, string) {
+func ExecuteSample(ctx context.Context, sample Sample) (error, string) {
 	var model WaveFunctionCollapse.WFCModel
 	var err error
 
@@ -114,6 +132,10 @@ func ExecuteSample(sample Sample) (error, string) {
 		model, err = Overlapping(sample)
 	case "tiled":
 		model, err = Tiled(sample)
+	case "voxel":
+		model, err = Voxel(sample)
+	case "hex":
+		model, err = Hex(sample)
 	default:
 		model, err = nil, WaveFunctionCollapse.WFCError("type not recognized: "+sample.Type)
 	}
@@ -122,36 +144,94 @@ func ExecuteSample(sample Sample) (error, string) {
 		return err, ""
 	}
 
-	err = ExecuteModel(model, out)
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	result, err := ExecuteModel(ctx, model, out)
 
-	return err, out
+	return err, fmt.Sprintf("%s (seed %d, %d contradictions)", out, result.Seed, result.Contradictions)
 }
 
-func ExecuteModel(model WaveFunctionCollapse.WFCModel, outfile string) error {
-	result := false
+func ExecuteModel(ctx context.Context, model WaveFunctionCollapse.WFCModel, outfile string) (WaveFunctionCollapse.ParallelResult, error) {
+	base := *seed
+	if base == 0 {
+		base = time.Now().UTC().UnixNano()
+	}
+
+	model.SetSeed(base)
+	result := WaveFunctionCollapse.SolveParallel(ctx, model, *reps, *limit)
 
-	for k := 0; k < *reps; k++ {
-		result = model.Run(*limit)
-		if result {
-			break
-		}
+	switch result.Status {
+	case WaveFunctionCollapse.StatusSuccess, WaveFunctionCollapse.StatusLimitReached:
+	case WaveFunctionCollapse.StatusContradiction:
+		return result, WaveFunctionCollapse.WFCError(result.Contradiction.String())
+	default:
+		return result, result.Err
 	}
 
-	if !result {
-		return WaveFunctionCollapse.WFCError("contradiction")
+	img, ok := result.Model.(image.Image)
+	if !ok {
+		return result, WaveFunctionCollapse.WFCError("no model solved")
 	}
 
 	if writer, err := os.Create(outfile); err != nil {
-		return err
-	} else if err = png.Encode(writer, model); err != nil {
-		return err
+		return result, err
+	} else if err = png.Encode(writer, img); err != nil {
+		return result, err
 	}
 
-	return nil
+	return result, nil
 }
 
 func Tiled(sample Sample) (model WaveFunctionCollapse.WFCModel, err error) {
-	info := WaveFunctionCollapse.ModelInfo{}
+	info, err := LoadModelInfo(sample)
+	if err != nil {
+		return nil, err
+	}
+
+	tiled := WaveFunctionCollapse.NewTiledModel(info, sample.Width, sample.Height, sample.PeriodicOut, sample.Black, 0)
+	if err := tiled.SetBorders(sample.Borders); err != nil {
+		return nil, err
+	}
+	for name, file := range sample.WeightMaps {
+		img, err := LoadImage(sample.dir, file)
+		if err != nil {
+			return nil, err
+		}
+		if err := tiled.TileWeightMapImage(name, img); err != nil {
+			return nil, err
+		}
+	}
+	if err := Configure(tiled.Model, sample); err != nil {
+		return nil, err
+	}
+
+	return tiled, nil
+}
+
+func Voxel(sample Sample) (model WaveFunctionCollapse.WFCModel, err error) {
+	info, err := LoadModelInfo(sample)
+	if err != nil {
+		return nil, err
+	}
+
+	voxel, err := WaveFunctionCollapse.NewVoxelModel(info, sample.Width, sample.Height, sample.Depth,
+		sample.PeriodicOut, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := Configure(voxel.Model, sample); err != nil {
+		return nil, err
+	}
+
+	return voxel, nil
+}
+
+func Hex(sample Sample) (model WaveFunctionCollapse.WFCModel, err error) {
+	var info WaveFunctionCollapse.HexModelInfo
 
 	if data, err := ioutil.ReadFile(path.Join(sample.dir, sample.Name)); err != nil {
 		return nil, err
@@ -163,10 +243,33 @@ func Tiled(sample Sample) (model WaveFunctionCollapse.WFCModel, err error) {
 		info.Tiles[t].Dir = path.Dir(path.Join(sample.dir, sample.Name))
 	}
 
-	info.Initialize()
+	if err := info.Initialize(); err != nil {
+		return nil, err
+	}
 
-	model = WaveFunctionCollapse.NewTiledModel(info, sample.Width, sample.Height, sample.PeriodicOut, sample.Black)
-	return
+	hex, err := WaveFunctionCollapse.NewHexModel(info, sample.Width, sample.Height, sample.PeriodicOut, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := Configure(hex.Model, sample); err != nil {
+		return nil, err
+	}
+
+	return hex, nil
+}
+
+func LoadModelInfo(sample Sample) (info WaveFunctionCollapse.ModelInfo, err error) {
+	if data, err := ioutil.ReadFile(path.Join(sample.dir, sample.Name)); err != nil {
+		return info, err
+	} else if err = json.Unmarshal(data, &info); err != nil {
+		return info, err
+	}
+
+	for t := range info.Tiles {
+		info.Tiles[t].Dir = path.Dir(path.Join(sample.dir, sample.Name))
+	}
+
+	return info, info.Initialize()
 }
 
 func Overlapping(sample Sample) (model WaveFunctionCollapse.WFCModel, err error) {
@@ -178,9 +281,95 @@ func Overlapping(sample Sample) (model WaveFunctionCollapse.WFCModel, err error)
 		return nil, err
 	}
 
-	model = WaveFunctionCollapse.NewOverlappingModel(img, sample.N, sample.Width, sample.Height, sample.PeriodicIn,
-		sample.PeriodicOut, sample.Symmetry, sample.Ground)
-	return
+	overlapping := WaveFunctionCollapse.NewOverlappingModel(img, sample.N, sample.Width, sample.Height, sample.PeriodicIn,
+		sample.PeriodicOut, sample.Symmetry, sample.Ground, 0)
+	for _, anchor := range sample.Anchors {
+		if err := overlapping.AddAnchor(anchor); err != nil {
+			return nil, err
+		}
+	}
+	if sample.Target != "" {
+		if err := Inpaint(overlapping, sample); err != nil {
+			return nil, err
+		}
+	}
+	if err := Configure(overlapping.Model, sample); err != nil {
+		return nil, err
+	}
+
+	return overlapping, nil
+}
+
+func Inpaint(model *WaveFunctionCollapse.OverlappingModel, sample Sample) error {
+	target, err := LoadImage(sample.dir, sample.Target)
+	if err != nil {
+		return err
+	}
+
+	var mask image.Image
+	if sample.TargetMask != "" {
+		if mask, err = LoadImage(sample.dir, sample.TargetMask); err != nil {
+			return err
+		}
+	}
+
+	return model.Inpaint(target, mask)
+}
+
+func LoadImage(dir, name string) (image.Image, error) {
+	file, err := os.Open(path.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+func Configure(model *WaveFunctionCollapse.Model, sample Sample) error {
+	if sample.Backtrack {
+		model.SetBacktracking(sample.BacktrackBudget, sample.BacktrackDepth)
+	}
+
+	heuristic, err := Heuristic(sample)
+	if err != nil {
+		return err
+	}
+	model.SetHeuristic(heuristic)
+
+	if sample.Mask != "" {
+		img, err := LoadImage(sample.dir, sample.Mask)
+		if err != nil {
+			return err
+		}
+		return model.SetMaskImage(img)
+	}
+
+	return nil
+}
+
+func Heuristic(sample Sample) (WaveFunctionCollapse.Heuristic, error) {
+	switch sample.Heuristic {
+	case "", "entropy":
+		return &WaveFunctionCollapse.MinEntropyQueue{}, nil
+	case "entropy-scan":
+		return &WaveFunctionCollapse.MinEntropy{}, nil
+	case "mrv":
+		return &WaveFunctionCollapse.MinRemainingValues{}, nil
+	case "scanline":
+		return &WaveFunctionCollapse.Scanline{}, nil
+	case "random":
+		return &WaveFunctionCollapse.RandomOrder{}, nil
+	case "growth":
+		growth := &WaveFunctionCollapse.Growth{X: sample.Width / 2, Y: sample.Height / 2}
+		if len(sample.Origin) == 2 {
+			growth.X, growth.Y = sample.Origin[0], sample.Origin[1]
+		}
+		return growth, nil
+	default:
+		return nil, WaveFunctionCollapse.WFCError("heuristic not recognized: " + sample.Heuristic)
+	}
 }
 
 func OutputFile(base string) (outfile string) {
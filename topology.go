@@ -0,0 +1,59 @@
+package WaveFunctionCollapse
+
+// Topology describes the cells of a model and how they are connected. Neighbor returns the cell next to cell i in
+// direction d, neighbours must be symmetric: j is the neighbour of i in direction d if and only if i is the
+// neighbour of j in the opposite direction. Boundary cells are not observed and bans are not propagated into them.
+type Topology interface {
+	Size() int
+	Directions() int
+	Opposite(d int) int
+	Neighbor(i, d int) (int, bool)
+	Boundary(i int) bool
+}
+
+// SquareGrid connects the cells of a Width x Height grid in the Dx, Dy directions. Unless the grid is periodic,
+// the cells within Margin of the right and bottom edge are boundary cells.
+type SquareGrid struct {
+	Width, Height, Margin int
+	Periodic              bool
+}
+
+func (grid SquareGrid) Size() int {
+	return grid.Width * grid.Height
+}
+
+func (grid SquareGrid) Directions() int {
+	return 4
+}
+
+func (grid SquareGrid) Opposite(d int) int {
+	return Opposite[d]
+}
+
+func (grid SquareGrid) Neighbor(i, d int) (int, bool) {
+	x, y := i%grid.Width+Dx[d], i/grid.Width+Dy[d]
+
+	if grid.Periodic {
+		x = (x + grid.Width) % grid.Width
+		y = (y + grid.Height) % grid.Height
+	} else if x < 0 || y < 0 || x >= grid.Width || y >= grid.Height {
+		return 0, false
+	}
+
+	return x + y*grid.Width, true
+}
+
+func (grid SquareGrid) Boundary(i int) bool {
+	x, y := i%grid.Width, i/grid.Width
+	return !grid.Periodic && (x+grid.Margin >= grid.Width || y+grid.Margin >= grid.Height)
+}
+
+// boundaryGrid is a square grid whose boundary cells are given by the deprecated OnBoundary function of a model
+type boundaryGrid struct {
+	SquareGrid
+	onBoundary func(x, y int) bool
+}
+
+func (grid boundaryGrid) Boundary(i int) bool {
+	return grid.onBoundary(i%grid.Width, i/grid.Width)
+}
@@ -124,6 +124,15 @@ func RandomDistribution(a []float64, r float64) int {
 	return 0
 }
 
+func contains(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func SumDistribution(a []float64) (sum float64) {
 	for _, v := range a {
 		sum += v
@@ -0,0 +1,315 @@
+package WaveFunctionCollapse
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+const (
+	Up   = 4
+	Down = 5
+)
+
+// CubicGrid connects the cells of a Width x Height x Depth grid in the 4 planar directions and the Up and Down
+// directions. Periodic wraps the planar directions and PeriodicZ wraps the vertical direction.
+type CubicGrid struct {
+	Width, Height, Depth int
+	Periodic, PeriodicZ  bool
+}
+
+func (grid CubicGrid) Size() int {
+	return grid.Width * grid.Height * grid.Depth
+}
+
+func (grid CubicGrid) Directions() int {
+	return 6
+}
+
+func (grid CubicGrid) Opposite(d int) int {
+	switch d {
+	case Up:
+		return Down
+	case Down:
+		return Up
+	default:
+		return Opposite[d]
+	}
+}
+
+func (grid CubicGrid) Neighbor(i, d int) (int, bool) {
+	layer := grid.Width * grid.Height
+	x, y, z := i%grid.Width, i%layer/grid.Width, i/layer
+
+	switch d {
+	case Up:
+		z++
+	case Down:
+		z--
+	default:
+		x, y = x+Dx[d], y+Dy[d]
+	}
+
+	if grid.Periodic {
+		x = (x + grid.Width) % grid.Width
+		y = (y + grid.Height) % grid.Height
+	} else if x < 0 || y < 0 || x >= grid.Width || y >= grid.Height {
+		return 0, false
+	}
+
+	if grid.PeriodicZ {
+		z = (z + grid.Depth) % grid.Depth
+	} else if z < 0 || z >= grid.Depth {
+		return 0, false
+	}
+
+	return x + y*grid.Width + z*layer, true
+}
+
+func (grid CubicGrid) Boundary(i int) bool {
+	return false
+}
+
+// VoxelModel is a tiled model in three dimensions. Every tile is a block of TileSize^3 voxels, loaded from TileSize
+// slice images per orientation ordered from bottom to top. Tiles are rotated and reflected around the vertical
+// axis, the vertical edges of the model info list which tile may be placed below which.
+type VoxelModel struct {
+	*Model
+
+	Fmz       int
+	TileSize  int
+	Tiles     [][]color.Color
+	TileNames []string
+}
+
+func NewVoxelModel(info ModelInfo, width, height, depth int, periodic bool, seed int64) (model *VoxelModel, err error) {
+	for _, tile := range info.Tiles {
+		_, _, cardinality := SymmetryFunc(tile.Symmetry)
+		files := info.Size
+		if tile.Unique {
+			files *= cardinality
+		}
+		if len(tile.images) < files {
+			return nil, WFCError(fmt.Sprintf("tile %s needs %d slice files", tile.Name, files))
+		}
+	}
+
+	model = &VoxelModel{
+		Model: &Model{
+			Rules:      &Rules{Topology: CubicGrid{Width: width, Height: height, Depth: depth, Periodic: periodic}},
+			Fmx:        width,
+			Fmy:        height,
+			Periodic:   periodic,
+			RandomSeed: seed,
+		},
+		Fmz:      depth,
+		TileSize: info.Size,
+	}
+
+	model.Tiles = make([][]color.Color, 0)
+	model.TileNames = make([]string, 0)
+	model.Weights = make([]float64, 0)
+
+	action, firstOccurrence := symmetryActions(info.Tiles)
+	size := model.TileSize
+
+	for _, tile := range info.Tiles {
+		_, _, cardinality := SymmetryFunc(tile.Symmetry)
+		model.T = len(model.Tiles)
+
+		if tile.Unique {
+			for t := 0; t < cardinality; t++ {
+				model.Tiles = append(model.Tiles, model.Block(tile.images[t*size:(t+1)*size]))
+				model.TileNames = append(model.TileNames, fmt.Sprintf("%s %d", tile.Name, t))
+			}
+		} else {
+			model.Tiles = append(model.Tiles, model.Block(tile.images[:size]))
+			model.TileNames = append(model.TileNames, fmt.Sprintf("%s %d", tile.Name, 0))
+
+			for t := 1; t < cardinality; t++ {
+				model.Tiles = append(model.Tiles, model.Rotate(model.Tiles[model.T+t-1]))
+				model.TileNames = append(model.TileNames, fmt.Sprintf("%s %d", tile.Name, t))
+			}
+		}
+
+		for t := 0; t < cardinality; t++ {
+			model.Weights = append(model.Weights, tile.Weight)
+		}
+	}
+
+	model.T = len(action)
+
+	planar := planarPropagator(info.Edges, action, firstOccurrence)
+	tempPropagator := append(planar, newPropagator(2, model.T)...)
+
+	//vertical adjacency is preserved by every symmetry action around the vertical axis
+	for _, edge := range info.Vertical {
+		bottomName, bottomCardinal, topName, topCardinal := ParseEdge(edge)
+		for _, name := range []string{bottomName, topName} {
+			if _, ok := firstOccurrence[name]; !ok {
+				return nil, WFCError("tile not recognized: " + name)
+			}
+		}
+		if bottomCardinal < 0 || bottomCardinal >= 8 || topCardinal < 0 || topCardinal >= 8 {
+			return nil, WFCError("cardinal out of range")
+		}
+
+		b := action[firstOccurrence[bottomName]][bottomCardinal]
+		t := action[firstOccurrence[topName]][topCardinal]
+
+		for k := 0; k < 8; k++ {
+			tempPropagator[Up][action[b][k]][action[t][k]] = true
+			tempPropagator[Down][action[t][k]][action[b][k]] = true
+		}
+	}
+
+	model.Propagator = sparsePropagator(tempPropagator)
+
+	return model, nil
+}
+
+// SelectVoxel restricts the cell at x, y, z to pattern t
+func (model *VoxelModel) SelectVoxel(x, y, z, t int) error {
+	i, err := model.cell(x, y, z)
+	if err != nil {
+		return err
+	}
+	return model.SelectCell(i, t)
+}
+
+// BanVoxel forbids pattern t in the cell at x, y, z
+func (model *VoxelModel) BanVoxel(x, y, z, t int) error {
+	i, err := model.cell(x, y, z)
+	if err != nil {
+		return err
+	}
+	return model.BanCell(i, t)
+}
+
+func (model *VoxelModel) cell(x, y, z int) (int, error) {
+	if x < 0 || y < 0 || z < 0 || x >= model.Fmx || y >= model.Fmy || z >= model.Fmz {
+		return 0, WFCError("cell out of range")
+	}
+	return x + y*model.Fmx + z*model.Fmx*model.Fmy, nil
+}
+
+// Block reads a voxel block from its slices, ordered from bottom to top
+func (model *VoxelModel) Block(slices []image.Image) (result []color.Color) {
+	size := model.TileSize
+	result = make([]color.Color, size*size*size)
+	for z, img := range slices {
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				result[x+y*size+z*size*size] = img.At(x, y)
+			}
+		}
+	}
+	return
+}
+
+// Rotate rotates a voxel block a quarter turn around the vertical axis
+func (model *VoxelModel) Rotate(block []color.Color) (result []color.Color) {
+	size := model.TileSize
+	result = make([]color.Color, len(block))
+	for z := 0; z < size; z++ {
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				result[x+y*size+z*size*size] = block[size-1-y+x*size+z*size*size]
+			}
+		}
+	}
+	return
+}
+
+// Voxels returns the observed tiles indexed by [z][y][x], -1 for masked cells, or nil if the model is not observed
+func (model *VoxelModel) Voxels() [][][]int {
+	if model.Observed == nil {
+		return nil
+	}
+
+	voxels := make([][][]int, model.Fmz)
+	for z := range voxels {
+		voxels[z] = make([][]int, model.Fmy)
+		for y := range voxels[z] {
+			voxels[z][y] = make([]int, model.Fmx)
+			for x := range voxels[z][y] {
+				voxels[z][y][x] = model.Observed[x+y*model.Fmx+z*model.Fmx*model.Fmy]
+			}
+		}
+	}
+	return voxels
+}
+
+// VoxelColor returns the color of voxel x, y, z of the output, averaging the remaining tiles of unobserved cells
+func (model *VoxelModel) VoxelColor(x, y, z int) color.Color {
+	size := model.TileSize
+	i := x/size + y/size*model.Fmx + z/size*model.Fmx*model.Fmy
+	v := x%size + y%size*size + z%size*size*size
+
+	if model.Masked(i) {
+		return model.ColorModel().Convert(color.Transparent)
+	} else if model.Observed != nil {
+		return model.ColorModel().Convert(model.Tiles[model.Observed[i]][v])
+	}
+
+	var r, g, b, a, sum float64
+	for t := 0; t < model.T; t++ {
+		if model.Possible(i, t) {
+			cr, cg, cb, ca := model.Tiles[t][v].RGBA()
+			w := model.Weights[t]
+			r, g, b, a, sum = r+float64(cr)*w, g+float64(cg)*w, b+float64(cb)*w, a+float64(ca)*w, sum+w
+		}
+	}
+
+	if sum == 0 {
+		return model.ColorModel().Convert(color.RGBA{})
+	}
+
+	return model.ColorModel().Convert(color.RGBA64{
+		R: uint16(r / sum),
+		G: uint16(g / sum),
+		B: uint16(b / sum),
+		A: uint16(a / sum),
+	})
+}
+
+// Slice returns the image of voxel layer z, layers are counted from the bottom
+func (model *VoxelModel) Slice(z int) image.Image {
+	return &voxelSlice{model: model, z: z}
+}
+
+// Slices returns the number of voxel layers of the output
+func (model *VoxelModel) Slices() int {
+	return model.Fmz * model.TileSize
+}
+
+func (model *VoxelModel) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds of the voxel model as an image, the slices are stacked from top (bottom slice) to bottom (top slice)
+func (model *VoxelModel) Bounds() image.Rectangle {
+	return image.Rect(0, 0, model.Fmx*model.TileSize, model.Fmy*model.TileSize*model.Slices())
+}
+
+func (model *VoxelModel) At(x, y int) color.Color {
+	height := model.Fmy * model.TileSize
+	return model.VoxelColor(x, y%height, y/height)
+}
+
+type voxelSlice struct {
+	model *VoxelModel
+	z     int
+}
+
+func (slice *voxelSlice) ColorModel() color.Model {
+	return slice.model.ColorModel()
+}
+
+func (slice *voxelSlice) Bounds() image.Rectangle {
+	return image.Rect(0, 0, slice.model.Fmx*slice.model.TileSize, slice.model.Fmy*slice.model.TileSize)
+}
+
+func (slice *voxelSlice) At(x, y int) color.Color {
+	return slice.model.VoxelColor(x, y, slice.z)
+}
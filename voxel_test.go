@@ -0,0 +1,56 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"testing"
+)
+
+// testVoxelTiles returns the path tiles as blocks of identical slices that may only be stacked on themselves
+func testVoxelTiles() ModelInfo {
+	info := testTiles()
+	for k, tile := range info.Tiles {
+		info.Tiles[k].images = []image.Image{tile.images[0], tile.images[0], tile.images[0]}
+		info.Vertical = append(info.Vertical, Edge{tile.Name, tile.Name})
+	}
+	return info
+}
+
+func TestVoxelModelMissingSlices(t *testing.T) {
+	if _, err := NewVoxelModel(testTiles(), 4, 4, 4, false, 1); err == nil {
+		t.Fatal("tiles with a single slice were accepted")
+	}
+
+	info := testVoxelTiles()
+	info.Tiles[1].Unique = true
+	if _, err := NewVoxelModel(info, 4, 4, 4, false, 1); err == nil {
+		t.Fatal("a unique tile without slices for every orientation was accepted")
+	}
+}
+
+func TestVoxelModelSelectVoxel(t *testing.T) {
+	model, err := NewVoxelModel(testVoxelTiles(), 6, 6, 3, false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.SetBacktracking(0, 0)
+
+	cross := -1
+	for p, name := range model.TileNames {
+		if name == "cross 0" {
+			cross = p
+		}
+	}
+	if err := model.SelectVoxel(1, 3, 2, cross); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.SelectVoxel(1, 3, 3, cross); err == nil {
+		t.Fatal("a cell above the top layer was accepted")
+	}
+
+	if !model.Run(0) {
+		t.Fatal("run failed")
+	}
+	if voxel := model.Voxels()[2][3][1]; voxel != cross {
+		t.Fatalf("the selected voxel holds %d", voxel)
+	}
+}
@@ -0,0 +1,118 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// weight returns the weight of pattern t in cell i and its weight log weight
+func (model *Model) weight(i, t int) (w, wlw float64) {
+	if model.WeightMaps == nil || model.WeightMaps[t] == nil {
+		return model.Weights[t], model.WeightLogWeights[t]
+	}
+
+	w = model.Weights[t] * model.WeightMaps[t](i)
+	if w <= 0 {
+		return 0, 0
+	}
+	return w, w * math.Log10(w)
+}
+
+// entropy returns the entropy of a cell from its sums, a cell without any weight left has none
+func entropy(sum, sumOfWeightLogWeights float64) float64 {
+	if sum <= 0 {
+		return 0
+	}
+	return math.Log10(sum) - sumOfWeightLogWeights/sum
+}
+
+// SetWeightMap multiplies the weights of the patterns in every cell i by field(i). A pattern with weight zero is
+// only observed when no other pattern is left, a cell with only such patterns left picks one of them uniformly. The
+// field is shared with the clones of the model and must be safe for concurrent use.
+func (model *Model) SetWeightMap(patterns []int, field func(i int) float64) error {
+	for _, t := range patterns {
+		if t < 0 || t >= model.T {
+			return WFCError("pattern out of range")
+		}
+	}
+
+	maps := make([]func(i int) float64, model.T)
+	copy(maps, model.WeightMaps)
+	for _, t := range patterns {
+		maps[t] = field
+	}
+	model.WeightMaps = maps
+	return nil
+}
+
+// SetWeightMapImage scales a grayscale image to the cells of the output and multiplies the weights of the patterns
+// by the brightness of the pixel of each cell, from 0 for black to 1 for white. Models whose cells do not form an
+// Fmx x Fmy grid, such as voxel models, can not use an image.
+func (model *Model) SetWeightMapImage(patterns []int, img image.Image) error {
+	field, err := imageField(img, model.Fmx, model.Fmy, func(c color.Color) float64 {
+		return float64(color.GrayModel.Convert(c).(color.Gray).Y) / 255
+	})
+	if err != nil {
+		return err
+	}
+	if len(field) != model.grid().Size() {
+		return WFCError("weight map size does not match the model")
+	}
+
+	return model.SetWeightMap(patterns, func(i int) float64 {
+		return field[i]
+	})
+}
+
+func (model *Model) ClearWeightMaps() {
+	model.WeightMaps = nil
+}
+
+// imageField samples an image at the cells of a width x height grid
+func imageField(img image.Image, width, height int, f func(c color.Color) float64) ([]float64, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, WFCError("image is empty")
+	}
+
+	field := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			px := bounds.Min.X + x*bounds.Dx()/width
+			py := bounds.Min.Y + y*bounds.Dy()/height
+			field[x+y*width] = f(img.At(px, py))
+		}
+	}
+	return field, nil
+}
+
+// TileWeightMap multiplies the weights of a tile name in the cell at x, y by field(x, y)
+func (model *TiledModel) TileWeightMap(name string, field func(x, y int) float64) error {
+	patterns, err := model.TilePatterns(name)
+	if err != nil {
+		return err
+	}
+
+	return model.SetWeightMap(patterns, func(i int) float64 {
+		return field(i%model.Fmx, i/model.Fmx)
+	})
+}
+
+// TileWeightMapImage multiplies the weights of a tile name by a grayscale image
+func (model *TiledModel) TileWeightMapImage(name string, img image.Image) error {
+	patterns, err := model.TilePatterns(name)
+	if err != nil {
+		return err
+	}
+	return model.SetWeightMapImage(patterns, img)
+}
+
+// ColorWeightMapImage multiplies the weights of the patterns that give their cell color c by a grayscale image
+func (model *OverlappingModel) ColorWeightMapImage(c color.Color, img image.Image) error {
+	patterns := model.ColorPatterns(c)
+	if len(patterns) == 0 {
+		return WFCError("color does not occur in the sample")
+	}
+	return model.SetWeightMapImage(patterns, img)
+}
@@ -0,0 +1,57 @@
+package WaveFunctionCollapse
+
+import (
+	"image"
+	"testing"
+)
+
+func TestWeightMapZeroRegion(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		model := NewTiledModel(testTiles(), 16, 16, false, false, seed)
+		model.SetBacktracking(1000, 0)
+
+		patterns := make([]int, model.T)
+		for p := range patterns {
+			patterns[p] = p
+		}
+		//every pattern has weight zero in the left half
+		err := model.SetWeightMap(patterns, func(i int) float64 {
+			if i%model.Fmx < 8 {
+				return 0
+			}
+			return 1
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !model.Run(0) {
+			t.Fatalf("seed %d: run failed", seed)
+		}
+		for i, p := range model.Observed {
+			if p < 0 || model.SumsOfOnes[i] != 1 {
+				t.Fatalf("seed %d: cell %d is not observed", seed, i)
+			}
+		}
+		if !validAdjacency(model.Model) {
+			t.Fatalf("seed %d: neighbouring cells do not agree", seed)
+		}
+	}
+}
+
+func TestWeightMapImageSize(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+
+	tiled := NewTiledModel(testTiles(), 4, 4, false, false, 1)
+	if err := tiled.TileWeightMapImage("cross", img); err != nil {
+		t.Error(err)
+	}
+
+	voxel, err := NewVoxelModel(testVoxelTiles(), 4, 4, 3, false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := voxel.SetWeightMapImage([]int{0}, img); err == nil {
+		t.Error("weight map image was accepted by a voxel model")
+	}
+}